@@ -101,6 +101,38 @@ func (ca *RemoteChainOpImpl) nonce(addr string) (uint64, error) {
 	return uint64(ret.Data.(float64)), nil
 }
 
+func (ca *RemoteChainOpImpl) suggestGasPrice() (uint64, error) {
+	ret := ca.request("suggestGasPrice")
+	if !ret.IsSuccess() {
+		return 0, fmt.Errorf(ret.Message)
+	}
+	suggestion, ok := ret.Data.(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("unexpected suggestion %v", ret.Data)
+	}
+	standard, ok := suggestion["standard"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected suggestion %v", ret.Data)
+	}
+	return uint64(standard), nil
+}
+
+func (ca *RemoteChainOpImpl) chainID() (uint16, error) {
+	ret := ca.request("chainId")
+	if !ret.IsSuccess() {
+		return 0, fmt.Errorf(ret.Message)
+	}
+	info, ok := ret.Data.(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("unexpected chain id %v", ret.Data)
+	}
+	chainID, ok := info["chain_id"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected chain id %v", ret.Data)
+	}
+	return uint16(chainID), nil
+}
+
 // Endpoint returns current connected ip and port
 func (ca *RemoteChainOpImpl) Endpoint() string {
 	return fmt.Sprintf("%v:%v", ca.host, ca.port)
@@ -130,7 +162,22 @@ func (ca *RemoteChainOpImpl) SendRaw(tx *txRawData) *Result {
 		}
 		tx.Nonce = nonce
 	}
+	if tx.Gasprice == 0 {
+		gasPrice, err := ca.suggestGasPrice()
+		if err != nil {
+			return opError(err)
+		}
+		tx.Gasprice = gasPrice
+	}
 
+	// Sign for the chain of the connected node, so that it can't be replayed on other chains
+	if tx.ChainID == 0 {
+		chainID, err := ca.chainID()
+		if err != nil {
+			return opError(err)
+		}
+		tx.ChainID = chainID
+	}
 	tranx := txRawToTransaction(tx)
 	tranx.Hash = tranx.GenHash()
 	sign, err := privateKey.Sign(tranx.Hash.Bytes())
@@ -183,3 +230,7 @@ func (ca *RemoteChainOpImpl) TxReceipt(hash string) *Result {
 func (ca *RemoteChainOpImpl) Stake(addr string) *Result {
 	return ca.request("stake", addr)
 }
+
+func (ca *RemoteChainOpImpl) Unbondings(addr string) *Result {
+	return ca.request("unbondings", addr)
+}
@@ -20,6 +20,9 @@ type minerConfig struct {
 	rpcLevel      rpcLevel
 	rpcAddr       string
 	rpcPort       uint16
+	ws            bool
+	wsPort        uint16
+	wsOrigins     []string
 	super         bool
 	testMode      bool
 	natIP         string
@@ -35,3 +38,7 @@ type minerConfig struct {
 func (cfg *minerConfig) rpcEnable() bool {
 	return cfg.rpcLevel > rpcLevelNone
 }
+
+func (cfg *minerConfig) wsEnable() bool {
+	return cfg.rpcEnable() && cfg.ws
+}
@@ -154,6 +154,14 @@ func genStakeCmd() *stakeCmd {
 	return c
 }
 
+func genUnbondingsCmd() *stakeCmd {
+	c := &stakeCmd{
+		baseCmd: *genBaseCmd("unbondings", "get the pending unbondings of the reduced or undelegated stake"),
+	}
+	c.fs.StringVar(&c.addr, "addr", "", "the account address")
+	return c
+}
+
 func (c *stakeCmd) parse(args []string) bool {
 	if err := c.fs.Parse(args); err != nil {
 		output(err.Error())
@@ -310,6 +318,11 @@ func genGasBaseCmd(n string, h string) *gasBaseCmd {
 }
 
 func (c *gasBaseCmd) parseGasPrice() bool {
+	// Leave it to the suggested price of the node
+	if strings.TrimSpace(c.gasPriceStr) == "" {
+		c.gasPrice = 0
+		return true
+	}
 	gp, err := common.ParseCoin(c.gasPriceStr)
 	if err != nil {
 		output(fmt.Sprintf("%v:%v, correct example: 100AM,100kAM,1mAM,1DDAM", err, c.gasPriceStr))
@@ -321,16 +334,18 @@ func (c *gasBaseCmd) parseGasPrice() bool {
 
 func (c *gasBaseCmd) initBase() {
 	c.fs.Uint64Var(&c.gaslimit, "gaslimit", 3000, "gas limit, default 3000")
-	c.fs.StringVar(&c.gasPriceStr, "gasprice", "500AM", "gas price, default 500AM")
+	c.fs.StringVar(&c.gasPriceStr, "gasprice", "", "gas price, optional. will use the standard price suggested by the node if not specified")
 }
 
 type sendTxCmd struct {
 	gasBaseCmd
-	to     string
-	value  float64
-	data   string
-	nonce  uint64
-	txType int
+	to         string
+	value      float64
+	data       string
+	nonce      uint64
+	txType     int
+	validFrom  uint64
+	validUntil uint64
 }
 
 func genSendTxCmd() *sendTxCmd {
@@ -343,6 +358,8 @@ func genSendTxCmd() *sendTxCmd {
 	c.fs.StringVar(&c.data, "data", "", "transaction data")
 	c.fs.Uint64Var(&c.nonce, "nonce", 0, "nonce, optional. will use default nonce on core if not specified")
 	c.fs.IntVar(&c.txType, "type", 0, "transaction type: 0=general tx, 1=bind, 2=transfer bind, 3=unbind, 4=stake add, 5=stake reduce")
+	c.fs.Uint64Var(&c.validFrom, "validfrom", 0, "the lowest block height the transaction can be packed into, optional")
+	c.fs.Uint64Var(&c.validUntil, "validuntil", 0, "the highest block height the transaction can be packed into, optional")
 	return c
 }
 
@@ -355,6 +372,9 @@ func (c *sendTxCmd) toTxRaw() *txRawData {
 		Gas:      c.gaslimit,
 		Gasprice: c.gasPrice,
 		Nonce:    c.nonce,
+
+		ValidFrom:  c.validFrom,
+		ValidUntil: c.validUntil,
 	}
 }
 
@@ -371,6 +391,10 @@ func (c *sendTxCmd) parse(args []string) bool {
 		output("Not supported transaction type")
 		return false
 	}
+	if c.validUntil != 0 && c.validFrom > c.validUntil {
+		output("validfrom should not be higher than validuntil")
+		return false
+	}
 	if strings.TrimSpace(c.to) == "" {
 		output("please input the target address")
 		c.fs.PrintDefaults()
@@ -449,6 +473,7 @@ var cmdDelAccount = genBaseCmd("delaccount", "delete the info of the current unl
 var cmdConnect = genConnectCmd()
 var cmdBlockHeight = genBaseCmd("blockheight", "the current block height")
 var cmdStake = genStakeCmd()
+var cmdUnbondings = genUnbondingsCmd()
 var cmdTx = genTxCmd()
 var cmdBlock = genBlockCmd()
 var cmdSendTx = genSendTxCmd()
@@ -469,6 +494,7 @@ func init() {
 	list = append(list, &cmdNonce.baseCmd)
 	list = append(list, cmdAccountInfo)
 	list = append(list, &cmdStake.baseCmd)
+	list = append(list, &cmdUnbondings.baseCmd)
 	list = append(list, cmdDelAccount)
 	list = append(list, &cmdConnect.baseCmd)
 	list = append(list, cmdBlockHeight)
@@ -651,6 +677,13 @@ func loop(acm accountOp, chainOp chainOp) {
 					return chainOp.Stake(cmd.addr)
 				})
 			}
+		case cmdUnbondings.name:
+			cmd := genUnbondingsCmd()
+			if cmd.parse(args) {
+				handleCmd(func() *Result {
+					return chainOp.Unbondings(cmd.addr)
+				})
+			}
 		case cmdTx.name:
 			cmd := genTxCmd()
 			if cmd.parse(args) {
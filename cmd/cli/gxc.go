@@ -30,6 +30,7 @@ import (
 	"github.com/xchain/go-chain/common"
 	"github.com/xchain/go-chain/core"
 	"github.com/xchain/go-chain/network"
+	"github.com/xchain/go-chain/storage/xchaindb"
 	"gopkg.in/alecthomas/kingpin.v2"
 
 	"net/http"
@@ -37,6 +38,7 @@ import (
 	"runtime"
 	"runtime/debug"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -94,6 +96,71 @@ func (ddam *ddamApp) fullInit() error {
 		showMsg("proposer uses the package config: gasLimitForPackage %d ", gasLimitForPackage)
 	}
 
+	//set the strategy and the block size limit for proposer package
+	packingStrategy := conf.GetString("packing_strategy", core.DefaultPackingStrategy)
+	if !core.ValidPackingStrategy(packingStrategy) {
+		return fmt.Errorf("unknown packing strategy %v, should be %v or %v", packingStrategy, core.PackByGasPrice, core.PackByFeePerByte)
+	}
+	if packingStrategy != core.DefaultPackingStrategy {
+		core.TxPackingStrategy = packingStrategy
+		showMsg("proposer uses the package config: packingStrategy %s ", packingStrategy)
+	}
+	blockSizeForPackage := conf.GetInt("block_size_for_package", core.DefaultBlockSizeForPackage)
+	if blockSizeForPackage > 0 && blockSizeForPackage != core.DefaultBlockSizeForPackage {
+		core.BlockSizeForPackage = blockSizeForPackage
+		showMsg("proposer uses the package config: blockSizeForPackage %d ", blockSizeForPackage)
+	}
+
+	//set the min gas price bump percentage for replacing a pool transaction
+	priceBump := conf.GetInt("tx_price_bump", core.DefaultTxPriceBump)
+	if priceBump > 0 && priceBump != core.DefaultTxPriceBump {
+		core.TxPriceBump = uint64(priceBump)
+		showMsg("tx pool uses the replacement config: txPriceBump %d%% ", priceBump)
+	}
+
+	//set the max lifetime of the pool transactions
+	txLifetime := conf.GetInt("tx_lifetime", int(core.DefaultTxLifetime/time.Second))
+	if txLifetime > 0 && time.Duration(txLifetime)*time.Second != core.DefaultTxLifetime {
+		core.TxLifetime = time.Duration(txLifetime) * time.Second
+		showMsg("tx pool uses the lifetime config: txLifetime %ds ", txLifetime)
+	}
+
+	//set the max number of local transactions exempted from the pool eviction
+	txLocalLimit := conf.GetInt("tx_local_limit", core.DefaultTxLocalLimit)
+	if txLocalLimit > 0 && txLocalLimit != core.DefaultTxLocalLimit {
+		core.TxLocalLimit = txLocalLimit
+		showMsg("tx pool uses the local config: txLocalLimit %d ", txLocalLimit)
+	}
+
+	//set the sampled blocks and the price floor of the gas price oracle
+	oracleBlocks := conf.GetInt("gas_oracle_blocks", core.DefaultOracleBlocks)
+	if oracleBlocks > 0 && oracleBlocks != core.DefaultOracleBlocks {
+		core.OracleBlocks = oracleBlocks
+		showMsg("gas price oracle uses the config: oracleBlocks %d ", oracleBlocks)
+	}
+	lowerBound := global.Context().Config.GetSectionManager("core").GetInt("gasprice_lower_bound", core.DefaultOracleMinPrice)
+	if lowerBound > 0 {
+		core.OracleMinPrice = uint64(lowerBound)
+	}
+
+	//set if the historical states are pruned, and the number of the recent states kept
+	coreConf := global.Context().Config.GetSectionManager("core")
+	gcMode := coreConf.GetString("gc_mode", core.DefaultGCMode)
+	if !core.ValidGCMode(gcMode) {
+		return fmt.Errorf("unknown gc mode %v, should be %v or %v", gcMode, core.GCModeFull, core.GCModeArchive)
+	}
+	stateRetention := coreConf.GetInt("state_retention", core.DefaultStateRetention)
+	if stateRetention <= 0 {
+		return fmt.Errorf("state retention should be positive, got %v", stateRetention)
+	}
+	core.GCMode, core.StateRetention = gcMode, uint64(stateRetention)
+	if gcMode != core.DefaultGCMode {
+		showMsg("state uses the gc config: gcMode %s, stateRetention %d ", gcMode, stateRetention)
+	}
+	if err := initDBBackend(); err != nil {
+		return err
+	}
+
 	// Set current miner
 	miner := &types.Miner{
 		Addr:       common.HexToAddress(ddam.account.Address),
@@ -105,6 +172,31 @@ func (ddam *ddamApp) fullInit() error {
 	}
 	global.Context().Register("Current", miner)
 
+	//set the blocks the reduced or undelegated stake stays locked before it can be claimed
+	unbondingPeriod := conf.GetInt("unbonding_period", core.DefaultUnbondingPeriod)
+	if unbondingPeriod >= 0 && unbondingPeriod != core.DefaultUnbondingPeriod {
+		core.UnbondingPeriod = uint64(unbondingPeriod)
+		showMsg("stake uses the unbonding config: unbondingPeriod %d ", unbondingPeriod)
+	}
+
+	//set the chain id the transactions signed for, and the height from which the legacy ones are rejected
+	core.ChainID = cfg.chainID
+	forkHeight := conf.GetInt("chain_id_fork_height", -1)
+	if forkHeight >= 0 {
+		if cfg.chainID == 0 {
+			return fmt.Errorf("chain_id_fork_height requires a non-zero chain id")
+		}
+		core.ChainIDForkHeight = uint64(forkHeight)
+		showMsg("tx replay protection uses the config: chainID %d, forkHeight %d ", cfg.chainID, forkHeight)
+	}
+
+	//set the height from which the block headers commit to the merkle roots of the transactions and receipts
+	merkleForkHeight := conf.GetInt("merkle_roots_fork_height", -1)
+	if merkleForkHeight >= 0 {
+		core.MerkleRootsForkHeight = uint64(merkleForkHeight)
+		showMsg("block header uses the merkle roots config: forkHeight %d ", merkleForkHeight)
+	}
+
 	// Init network
 	netCfg := network.NetworkConfig{
 		IsSuper:         cfg.super,
@@ -142,6 +234,19 @@ func (ddam *ddamApp) fullInit() error {
 	return nil
 }
 
+// initDBBackend sets the storage engine of the databases by the config
+func initDBBackend() error {
+	backend := global.Context().Config.GetSectionManager("core").GetString("db_backend", xchaindb.DefaultBackend)
+	if !xchaindb.ValidBackend(backend) {
+		return fmt.Errorf("unknown db backend %v, should be %v or %v", backend, xchaindb.BackendLevelDB, xchaindb.BackendBolt)
+	}
+	if backend != xchaindb.DefaultBackend {
+		xchaindb.Backend = backend
+		showMsg("database uses the backend config: dbBackend %s ", backend)
+	}
+	return nil
+}
+
 // miner start miner node
 func (ddam *ddamApp) miner(cfg *minerConfig) error {
 	ddam.config = cfg
@@ -175,6 +280,12 @@ func (ddam *ddamApp) exit(ctrlC <-chan bool, quit chan<- bool) {
 		return
 	}
 	showMsg("exiting...")
+	// Write the states kept in the memory before the chain closes the database
+	if core.ChainStateImpl != nil {
+		if err := core.ChainStateImpl.Close(); err != nil {
+			showMsg("close chain state error:%v", err)
+		}
+	}
 	core.BlockChainImpl.Close()
 	xlog.Close()
 	if ddam.inited {
@@ -215,6 +326,9 @@ func (ddam *ddamApp) Run() {
 	enableMonitor := mineCmd.Flag("monitor", "enable monitor").Default("false").Bool()
 	addrRPC := mineCmd.Flag("rpcaddr", "rpc service host").Short('r').Default("0.0.0.0").IP()
 	rpcServicePort := mineCmd.Flag("rpcport", "rpc service port").Short('p').Default("8101").Uint16()
+	ws := mineCmd.Flag("ws", "start websocket rpc server for subscriptions, works with rpc").Bool()
+	wsPort := mineCmd.Flag("wsport", "websocket rpc service port").Default("8102").Uint16()
+	wsOrigins := mineCmd.Flag("wsorigins", "origins allowed to connect the websocket, separated by comma, only localhost if empty, * for any").Default("").String()
 	super := mineCmd.Flag("super", "start super node").Bool()
 	passWd := mineCmd.Flag("password", "login password").Default("123").String()
 
@@ -226,6 +340,16 @@ func (ddam *ddamApp) Run() {
 	natPort := mineCmd.Flag("natport", "nat server port").Default("0").Uint16()
 	chainID := mineCmd.Flag("chainid", "chain ID").Default("0").Uint16()
 
+	// State snapshot
+	snapshotCmd := app.Command("snapshot", "export or import the state snapshot offline")
+	exportCmd := snapshotCmd.Command("export", "export the state of the block to the snapshot file")
+	exportHeight := exportCmd.Flag("height", "the block height, default is the top block").Default("-1").Int64()
+	exportFile := exportCmd.Flag("file", "the snapshot file").Short('f').Required().String()
+	importCmd := snapshotCmd.Command("import", "rebuild the state from the snapshot file into a fresh database")
+	importFile := importCmd.Flag("file", "the snapshot file").Short('f').Required().String()
+	importDB := importCmd.Flag("db", "the empty database directory").Required().String()
+	importRoot := importCmd.Flag("root", "the trusted state root of the snapshot block, taken from a synced node").Required().String()
+
 	command, err := app.Parse(os.Args[1:])
 	if err != nil {
 		kingpin.Fatalf("%s, try --help", err)
@@ -241,6 +365,16 @@ func (ddam *ddamApp) Run() {
 			showMsg(err.Error())
 			os.Exit(0)
 		}
+	case exportCmd.FullCommand():
+		if err := exportSnapshot(*configFile, *exportHeight, *exportFile); err != nil {
+			showMsg("export snapshot error:%v", err)
+		}
+		os.Exit(0)
+	case importCmd.FullCommand():
+		if err := importSnapshot(*configFile, *importFile, *importDB, *importRoot); err != nil {
+			showMsg(err.Error())
+		}
+		os.Exit(0)
 	case mineCmd.FullCommand():
 		go func() {
 			http.ListenAndServe(fmt.Sprintf(":%d", *pprofPort), nil)
@@ -253,6 +387,9 @@ func (ddam *ddamApp) Run() {
 			rpcLevel:      rpcLevel(*rpc),
 			rpcAddr:       addrRPC.String(),
 			rpcPort:       *rpcServicePort,
+			ws:            *ws,
+			wsPort:        *wsPort,
+			wsOrigins:     strings.Split(*wsOrigins, ","),
 			super:         *super,
 			testMode:      *testMode,
 			natIP:         *natAddr,
@@ -19,6 +19,7 @@ import (
 	"net"
 
 	"github.com/xchain/go-chain/cmd/rpc"
+	"github.com/xchain/go-chain/core"
 
 	"fmt"
 	"strings"
@@ -52,8 +53,12 @@ func (gxc *ddamApp) initRpcInstances(br blockReader, tp txPool) error {
 	gxc.rpcInstances = make([]rpcApi, 0)
 
 	base := &baseRpcImpl{
-		br:     br,
-		txPool: tp,
+		br:        br,
+		txPool:    tp,
+		gasOracle: core.NewGasPriceOracle(br, tp, core.OracleBlocks, core.OracleMinPrice),
+	}
+	if gxc.config.wsEnable() {
+		base.events = newRpcEvents()
 	}
 	if level >= rpcLevelGx {
 		gxc.addInstance(&RpcGxImpl{
@@ -64,6 +69,9 @@ func (gxc *ddamApp) initRpcInstances(br blockReader, tp txPool) error {
 		gxc.addInstance(&RpcExplorerImpl{
 			baseRpcImpl: base,
 		})
+		gxc.addInstance(&RpcTxPoolImpl{
+			baseRpcImpl: base,
+		})
 	}
 	if level >= rpcLevelDev {
 		gxc.addInstance(&RpcDevImpl{
@@ -106,6 +114,32 @@ func startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string,
 	return nil
 }
 
+// startWS initializes and starts the websocket RPC endpoint, which supports the subscriptions
+func startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string) error {
+	// Generate the whitelist based on the allowed modules
+	whitelist := make(map[string]bool)
+	for _, module := range modules {
+		whitelist[module] = true
+	}
+	// Register all the APIs exposed by the services
+	handler := rpc.NewServer()
+	for _, api := range apis {
+		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
+			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+				return err
+			}
+		}
+	}
+	// All APIs registered, start the websocket listener
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	go rpc.NewWSServer(wsOrigins, handler).Serve(listener)
+
+	return nil
+}
+
 // StartRPC RPC function
 func (gxc *ddamApp) startRPC(reader blockReader, tp txPool) error {
 	var err error
@@ -125,12 +159,23 @@ func (gxc *ddamApp) startRPC(reader blockReader, tp txPool) error {
 		endpoint := fmt.Sprintf("%s:%d", host, port+uint16(plus))
 		err = startHTTP(endpoint, apis, []string{}, []string{}, []string{})
 		if err == nil {
-			return nil
+			break
 		}
 		if strings.Contains(err.Error(), "address already in use") {
 			continue
 		}
 		return err
 	}
-	return err
+	if err != nil {
+		return err
+	}
+
+	if gxc.config.wsEnable() {
+		endpoint := fmt.Sprintf("%s:%d", host, gxc.config.wsPort)
+		if err = startWS(endpoint, apis, []string{}, gxc.config.wsOrigins); err != nil {
+			return err
+		}
+		showMsg("websocket rpc service started at %v", endpoint)
+	}
+	return nil
 }
@@ -39,6 +39,10 @@ type txRawData struct {
 	Nonce    uint64 `json:"nonce"`
 	Data     []byte `json:"data"`
 	Sign     string `json:"sign"`
+
+	ValidFrom  uint64 `json:"valid_from,omitempty"`  // The lowest height of the block the transaction can be packed into
+	ValidUntil uint64 `json:"valid_until,omitempty"` // The highest height of the block the transaction can be packed into
+	ChainID    uint16 `json:"chain_id,omitempty"`    // The chain the transaction is signed for
 }
 
 func opError(err error) *Result {
@@ -77,6 +81,10 @@ func txRawToTransaction(tx *txRawData) *types.Transaction {
 		GasLimit: types.NewBigInt(tx.Gas),
 		GasPrice: types.NewBigInt(tx.Gasprice),
 		Sign:     crypto.HexToSign(tx.Sign),
+
+		ValidFrom:  tx.ValidFrom,
+		ValidUntil: tx.ValidUntil,
+		ChainID:    tx.ChainID,
 	}
 }
 
@@ -123,4 +131,6 @@ type chainOp interface {
 	TxReceipt(hash string) *Result
 
 	Stake(addr string) *Result
+
+	Unbondings(addr string) *Result
 }
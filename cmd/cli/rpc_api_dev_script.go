@@ -20,6 +20,7 @@ import (
 	"github.com/xchain/go-chain/crypto"
 
 	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/core"
 )
 
 func (api *RpcDevImpl) ScriptTransferTx(privateKey string, from string, to string, amount uint64, nonce uint64, txType int, gasPrice uint64) (*Result, error) {
@@ -37,6 +38,7 @@ func (api *RpcDevImpl) TxUnSafe(privateKey, target string, value, gas, gasprice,
 		Nonce:    nonce,
 		TxType:   txType,
 		Data:     []byte(data),
+		ChainID:  core.ChainID,
 	}
 	sk := crypto.HexToPrivateKey(privateKey)
 	if sk == nil {
@@ -72,8 +72,11 @@ func (api *RpcExplorerImpl) ExplorerBlockDetail(height uint64) (*Result, error)
 	return successResult(bd)
 }
 
-func (api *RpcExplorerImpl) GetUmidAddresses(addr common.Address) (*Result, error) {
-	db := api.br.LatestStateDB()
+func (api *RpcExplorerImpl) GetUmidAddresses(addr common.Address, height *uint64) (*Result, error) {
+	db, err := api.stateAt(height)
+	if err != nil {
+		return failResult(err.Error())
+	}
 
 	umidAddreses := dbGet(db, addr)
 	if umidAddreses == nil {
@@ -86,8 +89,12 @@ func (api *RpcExplorerImpl) GetUmidAddresses(addr common.Address) (*Result, erro
 	return successResult(target)
 }
 
-func (api *RpcExplorerImpl) GetPledge(addr common.Address) (*Result, error) {
-	db := api.br.LatestStateDB()
+// GetPledge returns the own stake of the miner at the height or the top block
+func (api *RpcExplorerImpl) GetPledge(addr common.Address, height *uint64) (*Result, error) {
+	db, err := api.stateAt(height)
+	if err != nil {
+		return failResult(err.Error())
+	}
 
 	data := db.GetData(addr, core.KeyOfstakeAmount)
 	if data == nil {
@@ -97,6 +104,39 @@ func (api *RpcExplorerImpl) GetPledge(addr common.Address) (*Result, error) {
 	return successResult(mount)
 }
 
+// GetPledgeDetail returns the stake of the miner at the height or the top block, including the stake delegated by each delegator
+func (api *RpcExplorerImpl) GetPledgeDetail(addr common.Address, height *uint64) (*Result, error) {
+	db, err := api.stateAt(height)
+	if err != nil {
+		return failResult(err.Error())
+	}
+
+	detail := core.GetPledgeDetail(db, addr)
+	if detail.Total().Sign() == 0 {
+		return failResult("Pledged information not found !!")
+	}
+	return successResult(convertPledge(detail))
+}
+
+// GetHTLC returns the hash time-locked transfers with the hash lock, one for each sender locked with it.
+// The preimage is revealed once it's claimed
+func (api *RpcExplorerImpl) GetHTLC(hashLock common.Hash) (*Result, error) {
+	db := api.br.LatestStateDB()
+
+	locks, err := core.GetHTLC(db, hashLock)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	if len(locks) == 0 {
+		return failResult("HTLC not found")
+	}
+	ret := make([]*HTLC, len(locks))
+	for i, h := range locks {
+		ret[i] = convertHTLC(h)
+	}
+	return successResult(ret)
+}
+
 func dbGet(db types.AccountDB, address common.Address) []byte {
 	return db.GetData(address, KeyOfUMIDAddr)
 }
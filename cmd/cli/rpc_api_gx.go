@@ -21,6 +21,7 @@ import (
 	"github.com/xchain/go-chain/common"
 	"github.com/xchain/go-chain/core"
 	"github.com/xchain/go-chain/global/types"
+	"github.com/xchain/go-chain/storage/account"
 	"math/big"
 	"strings"
 )
@@ -37,14 +38,22 @@ type blockReader interface {
 }
 
 type txPool interface {
+	core.PendingPricer
+	txPoolInspector
+
 	TxNum() uint64
 	GetReceipt(hash common.Hash) *types.Receipt
 	AddTransaction(tx *types.Transaction) (bool, error)
+
+	// AddLocalTransaction adds the transaction submitted through rpc as a local one
+	AddLocalTransaction(tx *types.Transaction) (bool, error)
 }
 
 type baseRpcImpl struct {
-	br     blockReader
-	txPool txPool
+	br        blockReader
+	txPool    txPool
+	gasOracle *core.GasPriceOracle
+	events    *rpcEvents // Feeds of the subscriptions, nil if websocket is not enabled
 }
 
 // RpcGtasImpl provides rpc service for users to interact with remote nodes
@@ -92,24 +101,56 @@ func (api *RpcGxImpl) Tx(txRawjson string) (*Result, error) {
 			return failResult("Wrong target address format")
 		}
 	}
-
+	if txRaw.ChainID != 0 && txRaw.ChainID != core.ChainID {
+		return failResult(fmt.Sprintf("transaction signed for chain %v, expect %v", txRaw.ChainID, core.ChainID))
+	}
+	if txRaw.ValidUntil != 0 && txRaw.ValidFrom > txRaw.ValidUntil {
+		return failResult(types.ErrTxInvalidWindow.Error())
+	}
 	trans := txRawToTransaction(txRaw)
 
 	trans.Hash = trans.GenHash()
 
 	if err := api.sendTransaction(trans); err != nil {
+		// Tell the user the gas price required to replace the pool one
+		if e, ok := err.(*core.ReplaceUnderpricedError); ok {
+			return &Result{
+				Message: e.Error(),
+				Data:    e.MinGasPrice,
+				Status:  -1,
+			}, nil
+		}
 		return failResult(err.Error())
 	}
 
 	return successResult(trans.Hash.Hex())
 }
 
-// Balance is query balance interface
-func (api *RpcGxImpl) Balance(account string) (*Result, error) {
+// ChainId returns the chain id the transactions should be signed with, and the height from which it is required if configured
+func (api *RpcGxImpl) ChainId() (*Result, error) {
+	info := &ChainIDInfo{ChainID: core.ChainID}
+	if core.ChainIDForkEnabled() {
+		forkHeight := core.ChainIDForkHeight
+		info.ForkHeight = &forkHeight
+	}
+	return successResult(info)
+}
+
+// SuggestGasPrice returns the slow, standard and fast gas prices according to the recent blocks and the pool
+func (api *RpcGxImpl) SuggestGasPrice() (*Result, error) {
+	return successResult(api.gasOracle.Suggest())
+}
+
+// Balance is query balance interface, at the height if specified
+func (api *RpcGxImpl) Balance(account string, height *uint64) (*Result, error) {
 	if !validateAddress(strings.TrimSpace(account)) {
 		return failResult("Wrong account address format")
 	}
-	b := api.br.GetBalance(common.HexToAddress(account))
+	db, err := api.stateAt(height)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	b := db.GetBalance(common.HexToAddress(account))
 
 	balance := common.AM2DDAM(b.Uint64())
 	return &Result{
@@ -162,13 +203,17 @@ func (api *RpcGxImpl) TransDetail(h string) (*Result, error) {
 	return successResult(nil)
 }
 
-func (api *RpcGxImpl) Nonce(addr string) (*Result, error) {
+func (api *RpcGxImpl) Nonce(addr string, height *uint64) (*Result, error) {
 	if !validateAddress(strings.TrimSpace(addr)) {
 		return failResult("Wrong account address format")
 	}
+	db, err := api.stateAt(height)
+	if err != nil {
+		return failResult(err.Error())
+	}
 	address := common.HexToAddress(addr)
 	// user will see the nonce as db nonce +1, so that user can use it directly when send a transaction
-	nonce := api.br.GetNonce(address) + 1
+	nonce := db.GetNonce(address) + 1
 	return successResult(nonce)
 }
 
@@ -188,24 +233,178 @@ func (api *RpcGxImpl) TxReceipt(h string) (*Result, error) {
 	return failResult("tx not exist")
 }
 
+// TxProof returns the header of the block including the transaction, with the merkle branches
+// proving the transaction and its receipt are in the block. They can be verified by
+// types.VerifyTxProof and types.VerifyReceiptProof without trusting the node
+func (api *RpcGxImpl) TxProof(h string) (*Result, error) {
+	if !validateHash(strings.TrimSpace(h)) {
+		return failResult("Wrong hash format")
+	}
+	hash := common.HexToHash(h)
+	rc := api.txPool.GetReceipt(hash)
+	if rc == nil {
+		return failResult("tx not exist")
+	}
+	if !core.MerkleRootsActivated(rc.Height) {
+		return failResult(fmt.Sprintf("block %v doesn't commit to the merkle roots, proof not supported", rc.Height))
+	}
+	b := api.br.QueryBlockByHeight(rc.Height)
+	if b == nil {
+		return failResult("block not found")
+	}
+	index := int(rc.TxIndex)
+	if index >= len(b.Transactions) || b.Transactions[index].Hash != hash {
+		return failResult("tx not found in the block")
+	}
+
+	receipts := make(types.Receipts, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		if receipts[i] = api.txPool.GetReceipt(tx.Hash); receipts[i] == nil {
+			return failResult(fmt.Sprintf("receipt of %v not found", tx.Hash.Hex()))
+		}
+	}
+	if _, err := core.VerifyMerkleRoots(b.Header, b.Transactions, receipts); err != nil {
+		return failResult(err.Error())
+	}
+	txProof, err := types.NewTxProof(b.Transactions, index)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	receiptProof, err := types.NewReceiptProof(receipts, index)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	return successResult(&TxProof{
+		Header:       b.Header,
+		Receipt:      rc,
+		TxProof:      txProof,
+		ReceiptProof: receiptProof,
+	})
+}
+
+// stateProver is implemented by the account db which can generate the merkle proofs
+type stateProver interface {
+	GetProof(addr common.Address) ([][]byte, error)
+	GetStorageProof(addr common.Address, key []byte) ([][]byte, error)
+}
+
+// GetProof returns the merkle proof of the account and its data of the keys in hex, against the state
+// root of the block at the height, or the top block if not specified. The proofs can be verified by
+// account.VerifyAccountProof and account.VerifyStorageProof without trusting the node
+func (api *RpcGxImpl) GetProof(address string, keys []string, height *uint64) (*Result, error) {
+	if !validateAddress(strings.TrimSpace(address)) {
+		return failResult("Wrong account address format")
+	}
+	var header *types.BlockHeader
+	if height == nil {
+		header = api.br.QueryTopBlock()
+	} else {
+		header = api.br.QueryBlockHeaderByHeight(*height)
+	}
+	if header == nil {
+		return failResult("block not found")
+	}
+	db, err := api.br.GetAccountDBByHash(header.Hash)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	prover, ok := db.(stateProver)
+	if !ok {
+		return failResult("state proof not supported")
+	}
+
+	addr := common.HexToAddress(address)
+	proof, err := prover.GetProof(addr)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	acc, err := account.VerifyAccountProof(header.StateTree, addr, proof)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	ret := &AccountProof{
+		Address:      addr,
+		Height:       header.Height,
+		BlockHash:    header.Hash,
+		StateRoot:    header.StateTree,
+		AccountProof: proofToHex(proof),
+		StorageProof: make([]*StorageProof, 0, len(keys)),
+	}
+	if acc == nil {
+		// The proof proves the absence of the account
+		return successResult(ret)
+	}
+	ret.Balance, ret.Nonce, ret.StorageRoot = acc.Balance, acc.Nonce, acc.Root
+	for _, k := range keys {
+		key := common.FromHex(k)
+		storageProof, err := prover.GetStorageProof(addr, key)
+		if err != nil {
+			return failResult(err.Error())
+		}
+		value, err := account.VerifyStorageProof(acc.Root, key, storageProof)
+		if err != nil {
+			return failResult(err.Error())
+		}
+		ret.StorageProof = append(ret.StorageProof, &StorageProof{
+			Key:   common.ToHex(key),
+			Value: common.ToHex(value),
+			Proof: proofToHex(storageProof),
+		})
+	}
+	return successResult(ret)
+}
+
 func (api *baseRpcImpl) sendTransaction(trans *types.Transaction) error {
 	if trans.Sign == nil {
 		return fmt.Errorf("transaction sign is empty")
 	}
 
-	if ok, err := api.txPool.AddTransaction(trans); err != nil || !ok {
+	if ok, err := api.txPool.AddLocalTransaction(trans); err != nil || !ok {
 		//common.DefaultLogger.Errorf("AddTransaction not ok or error:%s", err.Error())，
 		return err
 	}
 	return nil
 }
 
-func (api *baseRpcImpl) Stake(account string) (*Result, error) {
+// stateAt returns the state of the block at the height, or the latest state if not specified.
+// The latest state is read through the flat state of the chain state if it's initialised.
+// core.ErrStatePruned is returned if the state has been pruned
+func (api *baseRpcImpl) stateAt(height *uint64) (types.AccountDB, error) {
+	if core.ChainStateImpl == nil {
+		if height == nil {
+			return api.br.LatestStateDB(), nil
+		}
+		return nil, fmt.Errorf("chain state not initialised")
+	}
+	if height == nil {
+		return core.ChainStateImpl.LatestStateDB()
+	}
+	return core.ChainStateImpl.StateDBAt(*height)
+}
+
+// Unbondings returns the pending unbondings of the account with their maturity heights
+func (api *baseRpcImpl) Unbondings(account string) (*Result, error) {
 	if !validateAddress(strings.TrimSpace(account)) {
 		return failResult("Wrong account address format")
 	}
 	db := api.br.LatestStateDB()
 
+	q, err := core.GetUnbondings(db, common.HexToAddress(account))
+	if err != nil {
+		return failResult(err.Error())
+	}
+	return successResult(convertUnbondings(q, api.br.Height()))
+}
+
+func (api *baseRpcImpl) Stake(account string, height *uint64) (*Result, error) {
+	if !validateAddress(strings.TrimSpace(account)) {
+		return failResult("Wrong account address format")
+	}
+	db, err := api.stateAt(height)
+	if err != nil {
+		return failResult(err.Error())
+	}
+
 	data := db.GetData(common.HexToAddress(account), core.KeyOfstakeAmount)
 	if data == nil {
 		return failResult("Stake information not found !!")
@@ -0,0 +1,159 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either cliVersion 3 of the License, or
+//   (at your option) any later cliVersion.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xchain/go-chain/cmd/rpc"
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global"
+	"github.com/xchain/go-chain/global/types"
+	"github.com/xchain/go-chain/middleware/notify"
+)
+
+// eventFeed fans out the events of a bus topic to the rpc subscriptions,
+// so that the subscriptions coming and going don't touch the bus
+type eventFeed struct {
+	lock        sync.RWMutex
+	subscribers map[rpc.ID]func(message notify.Message)
+}
+
+func newEventFeed(topic string) *eventFeed {
+	f := &eventFeed{
+		subscribers: make(map[rpc.ID]func(message notify.Message)),
+	}
+	if ctx := global.Context(); ctx != nil && ctx.Bus != nil {
+		ctx.Bus.Subscribe(topic, f.send)
+	}
+	return f
+}
+
+func (f *eventFeed) subscribe(id rpc.ID, fn func(message notify.Message)) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.subscribers[id] = fn
+}
+
+func (f *eventFeed) unsubscribe(id rpc.ID) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.subscribers, id)
+}
+
+func (f *eventFeed) send(message notify.Message) {
+	f.lock.RLock()
+	subscribers := make([]func(message notify.Message), 0, len(f.subscribers))
+	for _, fn := range f.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	f.lock.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(message)
+	}
+}
+
+// rpcEvents holds the feeds of the events which can be subscribed through rpc
+type rpcEvents struct {
+	heads   *eventFeed // Blocks added on chain
+	pending *eventFeed // Transactions accepted by the pool
+}
+
+func newRpcEvents() *rpcEvents {
+	return &rpcEvents{
+		heads:   newEventFeed(notify.BlockAddSucc),
+		pending: newEventFeed(notify.TxAdded),
+	}
+}
+
+// subscribe creates a subscription on the connection and forwards the events of the feed to it
+// until the client unsubscribes or the connection is closed. Nothing is sent if convert returns nil
+func subscribe(ctx context.Context, feed *eventFeed, convert func(message notify.Message) interface{}) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported || feed == nil {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	feed.subscribe(sub.ID, func(message notify.Message) {
+		if data := convert(message); data != nil {
+			notifier.Notify(sub.ID, data)
+		}
+	})
+	go func() {
+		select {
+		case <-sub.Err():
+		case <-notifier.Closed():
+		}
+		feed.unsubscribe(sub.ID)
+	}()
+	return sub, nil
+}
+
+func (api *baseRpcImpl) headsFeed() *eventFeed {
+	if api.events == nil {
+		return nil
+	}
+	return api.events.heads
+}
+
+func (api *baseRpcImpl) pendingFeed() *eventFeed {
+	if api.events == nil {
+		return nil
+	}
+	return api.events.pending
+}
+
+// NewHeads notifies the header of each block added on chain
+func (api *RpcGxImpl) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	return subscribe(ctx, api.headsFeed(), func(message notify.Message) interface{} {
+		return convertBlockHeader(message.GetData().(*types.Block))
+	})
+}
+
+// NewPendingTransactions notifies the hash of each transaction accepted by the pool
+func (api *RpcGxImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	return subscribe(ctx, api.pendingFeed(), func(message notify.Message) interface{} {
+		return message.GetData().(*types.Transaction).Hash.Hex()
+	})
+}
+
+// Receipt notifies the receipt of the watched transaction once it is included in a block.
+// Receipt is checked on each new block, so the one included before subscribing comes with the next block
+func (api *RpcGxImpl) Receipt(ctx context.Context, h string) (*rpc.Subscription, error) {
+	if !validateHash(strings.TrimSpace(h)) {
+		return nil, fmt.Errorf("Wrong hash format")
+	}
+	hash := common.HexToHash(h)
+	var delivered int32
+	return subscribe(ctx, api.headsFeed(), func(message notify.Message) interface{} {
+		if atomic.LoadInt32(&delivered) == 1 {
+			return nil
+		}
+		rc := api.txPool.GetReceipt(hash)
+		if rc == nil || !atomic.CompareAndSwapInt32(&delivered, 0, 1) {
+			return nil
+		}
+		return convertExecutedTransaction(&types.ExecutedTransaction{
+			Receipt:     rc,
+			Transaction: api.br.GetTransactionByHash(true, hash),
+		})
+	})
+}
@@ -0,0 +1,75 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either cliVersion 3 of the License, or
+//   (at your option) any later cliVersion.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xchain/go-chain/cmd/rpc"
+	"github.com/xchain/go-chain/global/types"
+)
+
+func feedSize(f *eventFeed) int {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return len(f.subscribers)
+}
+
+func TestSubscribeNewPendingTransactions(t *testing.T) {
+	events := &rpcEvents{
+		heads:   newEventFeed(""),
+		pending: newEventFeed(""),
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("Gx", &RpcGxImpl{baseRpcImpl: &baseRpcImpl{events: events}}); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	ch := make(chan string, 1)
+	sub, err := client.Subscribe(context.Background(), "Gx", ch, "newPendingTransactions")
+	if err != nil {
+		t.Fatalf("subscribe error:%v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Wait for the subscription to be activated
+	for deadline := time.Now().Add(time.Second); feedSize(events.pending) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscription not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	tx := &types.Transaction{Nonce: 1}
+	tx.Hash = tx.GenHash()
+	events.pending.send(&types.TxAddedMessage{Tx: tx})
+
+	select {
+	case hash := <-ch:
+		if hash != tx.Hash.Hex() {
+			t.Fatalf("unexpected hash %v", hash)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription error:%v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("notification timeout")
+	}
+}
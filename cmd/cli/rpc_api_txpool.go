@@ -0,0 +1,96 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either cliVersion 3 of the License, or
+//   (at your option) any later cliVersion.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"strings"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/core"
+	"github.com/xchain/go-chain/global/types"
+)
+
+// txPoolInspector exposes the inner state of the pool for debugging
+type txPoolInspector interface {
+	Status() *core.TxPoolStatus
+	Content() (pending, queued map[common.Address][]*types.Transaction)
+	ContentFrom(source common.Address) (pending, queued []*types.Transaction)
+	Inspect(hash common.Hash) *core.TxInspection
+}
+
+// RpcTxPoolImpl provides rpc service for inspecting the transactions in the pool
+type RpcTxPoolImpl struct {
+	*baseRpcImpl
+}
+
+func (api *RpcTxPoolImpl) Namespace() string {
+	return "TxPool"
+}
+
+func (api *RpcTxPoolImpl) Version() string {
+	return "1"
+}
+
+// Status returns the number of pending and queued transactions in the pool
+func (api *RpcTxPoolImpl) Status() (*Result, error) {
+	return successResult(api.txPool.Status())
+}
+
+// Content returns all the transactions in the pool grouped by source and nonce
+func (api *RpcTxPoolImpl) Content() (*Result, error) {
+	pending, queued := api.txPool.Content()
+	content := &TxPoolContent{
+		Pending: make(map[string]map[uint64]*Transaction, len(pending)),
+		Queued:  make(map[string]map[uint64]*Transaction, len(queued)),
+	}
+	for source, txs := range pending {
+		content.Pending[source.Hex()] = convertTxsByNonce(txs)
+	}
+	for source, txs := range queued {
+		content.Queued[source.Hex()] = convertTxsByNonce(txs)
+	}
+	return successResult(content)
+}
+
+// ContentFrom returns the transactions of the given address in the pool
+func (api *RpcTxPoolImpl) ContentFrom(addr string) (*Result, error) {
+	if !validateAddress(strings.TrimSpace(addr)) {
+		return failResult("Wrong account address format")
+	}
+	source := common.HexToAddress(addr)
+	pending, queued := api.txPool.ContentFrom(source)
+	return successResult(&TxPoolAccountContent{
+		Nonce:   api.br.GetNonce(source),
+		Pending: convertTxsByNonce(pending),
+		Queued:  convertTxsByNonce(queued),
+	})
+}
+
+// Inspect tells whether the transaction is pending or queued, and why it is queued
+func (api *RpcTxPoolImpl) Inspect(hash string) (*Result, error) {
+	if !validateHash(strings.TrimSpace(hash)) {
+		return failResult("Wrong hash format")
+	}
+	return successResult(api.txPool.Inspect(common.HexToHash(hash)))
+}
+
+func convertTxsByNonce(txs []*types.Transaction) map[uint64]*Transaction {
+	ret := make(map[uint64]*Transaction, len(txs))
+	for _, tx := range txs {
+		ret[tx.Nonce] = convertTransaction(tx)
+	}
+	return ret
+}
@@ -47,10 +47,81 @@ func convertTransaction(tx *types.Transaction) *Transaction {
 		Data:     tx.Data,
 		Nonce:    tx.Nonce,
 		Value:    common.AM2DDAM(value),
+
+		ValidFrom:  tx.ValidFrom,
+		ValidUntil: tx.ValidUntil,
+		ChainID:    tx.ChainID,
+	}
+	if tx.MultiSign != nil {
+		trans.MultisigAccount = &tx.MultiSign.Account
+	}
+	if tx.Sponsor != nil {
+		trans.FeePayer = &tx.Sponsor.Payer
+	}
+	if tx.Type == types.TransactionTypeBatchTransfer {
+		if items, err := types.DecodeBatchTransfer(tx.Data); err == nil {
+			trans.Transfers = make([]*Transfer, len(items))
+			for i, item := range items {
+				trans.Transfers[i] = &Transfer{Target: item.Target, Value: common.AM2DDAM(item.Value.Uint64())}
+			}
+		}
 	}
 	return trans
 }
 
+func convertPledge(detail *core.PledgeDetail) *Pledge {
+	pledge := &Pledge{
+		Own:         common.AM2DDAM(detail.Own.Uint64()),
+		Delegated:   common.AM2DDAM(detail.Delegated.Uint64()),
+		Total:       common.AM2DDAM(detail.Total().Uint64()),
+		Delegations: make([]*Delegation, len(detail.Delegations)),
+	}
+	for i, d := range detail.Delegations {
+		pledge.Delegations[i] = &Delegation{Delegator: d.Delegator, Amount: common.AM2DDAM(d.Amount.Uint64())}
+	}
+	return pledge
+}
+
+func convertUnbondings(q types.UnbondingQueue, height uint64) *Unbondings {
+	matured, _ := q.Matured(height)
+	ret := &Unbondings{
+		Height:     height,
+		Claimable:  common.AM2DDAM(matured.Uint64()),
+		Unbondings: make([]*Unbonding, len(q)),
+	}
+	for i, u := range q {
+		ret.Unbondings[i] = &Unbonding{
+			Amount:   common.AM2DDAM(u.Amount.Uint64()),
+			Maturity: u.Maturity,
+			Matured:  u.Maturity <= height,
+		}
+	}
+	return ret
+}
+
+func proofToHex(proof [][]byte) []string {
+	ret := make([]string, len(proof))
+	for i, node := range proof {
+		ret[i] = common.ToHex(node)
+	}
+	return ret
+}
+
+func convertHTLC(h *types.HTLC) *HTLC {
+	ret := &HTLC{
+		HashLock:  h.HashLock,
+		Sender:    h.Sender,
+		Recipient: h.Recipient,
+		Amount:    common.AM2DDAM(h.Amount.Uint64()),
+		Timeout:   h.Timeout,
+		State:     h.State.String(),
+	}
+	if len(h.Preimage) > 0 {
+		ret.Preimage = common.ToHex(h.Preimage)
+	}
+	return ret
+}
+
 func convertExecutedTransaction(executed *types.ExecutedTransaction) *ExecutedTransaction {
 	rec := &Receipt{
 		Status:            int(executed.Receipt.Status),
@@ -59,6 +130,12 @@ func convertExecutedTransaction(executed *types.ExecutedTransaction) *ExecutedTr
 		Height:            executed.Receipt.Height,
 		TxIndex:           executed.Receipt.TxIndex,
 	}
+	if len(executed.Receipt.TransferStatus) > 0 {
+		rec.TransferStatus = make([]int, len(executed.Receipt.TransferStatus))
+		for i, status := range executed.Receipt.TransferStatus {
+			rec.TransferStatus[i] = int(status)
+		}
+	}
 	return &ExecutedTransaction{
 		Receipt:     rec,
 		Transaction: convertTransaction(executed.Transaction),
@@ -0,0 +1,161 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either cliVersion 3 of the License, or
+//   (at your option) any later cliVersion.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xchain/go-chain/auth"
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/core"
+	"github.com/xchain/go-chain/global"
+	"github.com/xchain/go-chain/global/types"
+	"github.com/xchain/go-chain/storage/account"
+	"github.com/xchain/go-chain/storage/xchaindb"
+)
+
+// exportSnapshot writes the state of the block at the height to the file, or of the top block if
+// the height is negative. The chain is opened offline, so the node should be stopped
+func exportSnapshot(confFile string, height int64, file string) error {
+	global.Init(confFile)
+	if err := initDBBackend(); err != nil {
+		return err
+	}
+	if err := core.InitCore(auth.NewIdentityManager()); err != nil {
+		return err
+	}
+	chain := core.BlockChainImpl
+	defer chain.Close()
+
+	var header *types.BlockHeader
+	if height < 0 {
+		header = chain.QueryTopBlock()
+	} else {
+		header = chain.QueryBlockHeaderByHeight(uint64(height))
+	}
+	if header == nil {
+		return fmt.Errorf("block at %v not found", height)
+	}
+	if core.ChainStateImpl == nil {
+		return fmt.Errorf("chain state not initialised")
+	}
+	db, err := core.ChainStateImpl.StateDBAt(header.Height)
+	if err != nil {
+		return err
+	}
+	state, ok := db.(*account.AccountDB)
+	if !ok {
+		return fmt.Errorf("state snapshot not supported")
+	}
+
+	// Write to a temporary file first, so a failed export leaves no partial snapshot
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	accounts, err := account.ExportSnapshot(f, state, account.SnapshotHeader{
+		Height:    header.Height,
+		BlockHash: header.Hash,
+		StateTree: header.StateTree,
+	})
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err == nil {
+		err = os.Rename(tmp, file)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	showMsg("exported %v accounts of block %v at height %v, state root %v", accounts, header.Hash.Hex(), header.Height, header.StateTree.Hex())
+	return nil
+}
+
+// importSnapshot rebuilds the state from the snapshot file into a fresh database in the directory on the
+// configured backend. The snapshot is only accepted if its state root is the trusted one given by the user,
+// as the block header recorded in the snapshot comes from the same untrusted file
+func importSnapshot(confFile string, file string, dir string, root string) error {
+	if !validateHash(strings.TrimSpace(root)) {
+		return fmt.Errorf("wrong state root format")
+	}
+	global.Init(confFile)
+	if err := initDBBackend(); err != nil {
+		return err
+	}
+	created := false
+	if info, err := os.Stat(dir); os.IsNotExist(err) {
+		created = true
+	} else if err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("database directory %v is not a directory", dir)
+	} else if entries, err := ioutil.ReadDir(dir); err != nil {
+		return err
+	} else if len(entries) > 0 {
+		return fmt.Errorf("database directory %v should be empty", dir)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ds, err := xchaindb.NewDataSource(dir, nil)
+	if err != nil {
+		if created {
+			os.RemoveAll(dir)
+		}
+		return err
+	}
+	header, accounts, err := core.ImportState(ds, f, common.HexToHash(strings.TrimSpace(root)))
+	// The data source is closed through a database on it
+	if db, closeErr := ds.NewPrefixDatabase(""); closeErr != nil {
+		if err == nil {
+			err = closeErr
+		}
+	} else {
+		db.Close()
+	}
+	if err != nil {
+		// Clean up the partial state, but keep the directory if it is given by the user
+		if created {
+			os.RemoveAll(dir)
+		} else {
+			removeDirEntries(dir)
+		}
+		return fmt.Errorf("import snapshot error:%v", err)
+	}
+	showMsg("imported %v accounts of block %v at height %v, state root %v", accounts, header.BlockHash.Hex(), header.Height, header.StateTree.Hex())
+	return nil
+}
+
+// removeDirEntries removes everything in the directory but the directory itself
+func removeDirEntries(dir string) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		os.RemoveAll(filepath.Join(dir, entry.Name()))
+	}
+}
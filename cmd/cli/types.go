@@ -162,6 +162,57 @@ type Transaction struct {
 	GasLimit uint64      `json:"gas_limit"`
 	GasPrice uint64      `json:"gas_price"`
 	Hash     common.Hash `json:"hash"`
+
+	Transfers       []*Transfer     `json:"transfers,omitempty"`
+	MultisigAccount *common.Address `json:"multisig_account,omitempty"`
+	ValidFrom       uint64          `json:"valid_from,omitempty"`
+	ValidUntil      uint64          `json:"valid_until,omitempty"`
+	ChainID         uint16          `json:"chain_id,omitempty"`
+	FeePayer        *common.Address `json:"fee_payer,omitempty"`
+}
+
+type ChainIDInfo struct {
+	ChainID    uint16  `json:"chain_id"`
+	ForkHeight *uint64 `json:"fork_height,omitempty"` // Transactions without chain id are rejected from this height if configured
+}
+
+type Pledge struct {
+	Own         float64       `json:"own"`
+	Delegated   float64       `json:"delegated"`
+	Total       float64       `json:"total"`
+	Delegations []*Delegation `json:"delegations"`
+}
+
+type Delegation struct {
+	Delegator common.Address `json:"delegator"`
+	Amount    float64        `json:"amount"`
+}
+
+type Unbonding struct {
+	Amount   float64 `json:"amount"`
+	Maturity uint64  `json:"maturity"` // The height from which it can be claimed
+	Matured  bool    `json:"matured"`
+}
+
+type Unbondings struct {
+	Height     uint64       `json:"height"`
+	Claimable  float64      `json:"claimable"`
+	Unbondings []*Unbonding `json:"unbondings"`
+}
+
+type HTLC struct {
+	HashLock  common.Hash    `json:"hash_lock"`
+	Sender    common.Address `json:"sender"`
+	Recipient common.Address `json:"recipient"`
+	Amount    float64        `json:"amount"`
+	Timeout   uint64         `json:"timeout"`
+	State     string         `json:"state"`
+	Preimage  string         `json:"preimage,omitempty"`
+}
+
+type Transfer struct {
+	Target common.Address `json:"target"`
+	Value  float64        `json:"value"`
 }
 
 type Receipt struct {
@@ -171,6 +222,33 @@ type Receipt struct {
 	TxHash  common.Hash `json:"transactionHash" gencodec:"required"`
 	Height  uint64      `json:"height"`
 	TxIndex uint16      `json:"tx_index"`
+
+	TransferStatus []int `json:"transferStatus,omitempty"`
+}
+
+type TxProof struct {
+	Header       *types.BlockHeader `json:"header"` // The raw header, its hash can be recomputed by the verifier
+	Receipt      *types.Receipt     `json:"receipt"`
+	TxProof      *types.MerkleProof `json:"tx_proof"`
+	ReceiptProof *types.MerkleProof `json:"receipt_proof"`
+}
+
+type StorageProof struct {
+	Key   string   `json:"key"`
+	Value string   `json:"value"`
+	Proof []string `json:"proof"`
+}
+
+type AccountProof struct {
+	Address      common.Address  `json:"address"`
+	Height       uint64          `json:"height"`
+	BlockHash    common.Hash     `json:"block_hash"`
+	StateRoot    common.Hash     `json:"state_root"`
+	Balance      *big.Int        `json:"balance"`
+	Nonce        uint64          `json:"nonce"`
+	StorageRoot  common.Hash     `json:"storage_root"`
+	AccountProof []string        `json:"account_proof"`
+	StorageProof []*StorageProof `json:"storage_proof"`
 }
 
 type ExecutedTransaction struct {
@@ -194,3 +272,14 @@ type ExplorerAccount struct {
 	Code      string                 `json:"code"`
 	StateData map[string]interface{} `json:"state_data"`
 }
+
+type TxPoolContent struct {
+	Pending map[string]map[uint64]*Transaction `json:"pending"`
+	Queued  map[string]map[uint64]*Transaction `json:"queued"`
+}
+
+type TxPoolAccountContent struct {
+	Nonce   uint64                  `json:"nonce"`
+	Pending map[uint64]*Transaction `json:"pending"`
+	Queued  map[uint64]*Transaction `json:"queued"`
+}
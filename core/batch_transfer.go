@@ -0,0 +1,80 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/xchain/go-chain/global/types"
+)
+
+const (
+	BatchTransferBaseGas = 1000 // Gas cost of a batch transfer transaction besides the transfers
+	BatchTransferItemGas = 400  // Gas cost of each transfer in the batch
+)
+
+// BatchTransferGas returns the intrinsic gas of a batch transfer transaction with n transfers
+func BatchTransferGas(n int) uint64 {
+	return BatchTransferBaseGas + BatchTransferItemGas*uint64(n)
+}
+
+// validateBatchTransfer checks the batch transfer transaction before it enters the pool.
+// The value and target of the transaction are not used, all transfers are listed in the data
+func validateBatchTransfer(tx *types.Transaction) error {
+	if tx.Target != nil {
+		return fmt.Errorf("batch transfer should not have target")
+	}
+	if tx.Value != nil && tx.Value.Sign() != 0 {
+		return fmt.Errorf("batch transfer should not have value")
+	}
+	items, err := types.DecodeBatchTransfer(tx.Data)
+	if err != nil {
+		return err
+	}
+	if gas := BatchTransferGas(len(items)); tx.GasLimit.Uint64() < gas {
+		return fmt.Errorf("gas limit too low, at least %v for %v transfers", gas, len(items))
+	}
+	return nil
+}
+
+// executeBatchTransfer applies the transfers of the transaction atomically, either all of them succeed or
+// none takes effect. It returns the status of the transaction, the status of each transfer and the gas used.
+// The failed transfer gets its own status and the others are marked as failed
+func executeBatchTransfer(db types.AccountDB, tx *types.Transaction) (status types.ReceiptStatus, transfers []types.ReceiptStatus, gasUsed uint64) {
+	items, err := types.DecodeBatchTransfer(tx.Data)
+	if err != nil {
+		logger.Debugf("batch transfer %v parse fail:%v", tx.Hash.Hex(), err)
+		return types.RSParseFail, nil, BatchTransferBaseGas
+	}
+	gasUsed = BatchTransferGas(len(items))
+	transfers = make([]types.ReceiptStatus, len(items))
+
+	snapshot := db.Snapshot()
+	for i, item := range items {
+		value := item.Value.Value()
+		if !db.CanTransfer(*tx.Source, value) {
+			db.RevertToSnapshot(snapshot)
+			for j := range transfers {
+				transfers[j] = types.RSFail
+			}
+			transfers[i] = types.RSBalanceNotEnough
+			return types.RSBalanceNotEnough, transfers, gasUsed
+		}
+		db.Transfer(*tx.Source, item.Target, value)
+		transfers[i] = types.RSSuccess
+	}
+	return types.RSSuccess, transfers, gasUsed
+}
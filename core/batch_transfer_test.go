@@ -0,0 +1,144 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+	"github.com/xchain/go-chain/storage/account"
+	"github.com/xchain/go-chain/storage/xchaindb"
+)
+
+func newTestAccountDB(t *testing.T) *account.AccountDB {
+	db, _ := xchaindb.NewMemDatabase()
+	state, err := account.NewAccountDB(common.Hash{}, account.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+// commitTestAccountDB commits the state and opens it again as the next block does
+func commitTestAccountDB(t *testing.T, db *account.AccountDB) *account.AccountDB {
+	root, err := db.Commit(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := account.NewAccountDB(root, db.Database())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func newBatchTransferTx(t *testing.T, source common.Address, values ...uint64) (*types.Transaction, []*types.TransferItem) {
+	items := make([]*types.TransferItem, len(values))
+	for i, v := range values {
+		items[i] = &types.TransferItem{Target: common.BytesToAddress([]byte{'r', byte(i)}), Value: types.NewBigInt(v)}
+	}
+	data, err := types.EncodeBatchTransfer(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := &types.Transaction{
+		Data:     data,
+		Nonce:    1,
+		Type:     types.TransactionTypeBatchTransfer,
+		GasLimit: types.NewBigInt(BatchTransferGas(len(items))),
+		GasPrice: types.NewBigInt(500),
+		Source:   &source,
+	}
+	tx.Hash = tx.GenHash()
+	return tx, items
+}
+
+func TestBatchTransfer_Execute(t *testing.T) {
+	db := newTestAccountDB(t)
+	source := common.BytesToAddress([]byte("payroll"))
+	db.AddBalance(source, big.NewInt(1000))
+
+	tx, items := newBatchTransferTx(t, source, 100, 200, 300)
+	if err := validateBatchTransfer(tx); err != nil {
+		t.Fatalf("validate error:%v", err)
+	}
+	status, transfers, gasUsed := executeBatchTransfer(db, tx)
+	if status != types.RSSuccess || len(transfers) != 3 || gasUsed != BatchTransferGas(3) {
+		t.Fatalf("unexpected result %v %v %v", status, transfers, gasUsed)
+	}
+	for i, item := range items {
+		if transfers[i] != types.RSSuccess || db.GetBalance(item.Target).Cmp(item.Value.Value()) != 0 {
+			t.Fatalf("transfer %v not applied", i)
+		}
+	}
+	if db.GetBalance(source).Uint64() != 400 {
+		t.Fatalf("unexpected source balance %v", db.GetBalance(source))
+	}
+}
+
+func TestBatchTransfer_Atomic(t *testing.T) {
+	db := newTestAccountDB(t)
+	source := common.BytesToAddress([]byte("payroll"))
+	db.AddBalance(source, big.NewInt(500))
+
+	tx, items := newBatchTransferTx(t, source, 100, 200, 300)
+	status, transfers, _ := executeBatchTransfer(db, tx)
+	if status != types.RSBalanceNotEnough {
+		t.Fatalf("unexpected status %v", status)
+	}
+	expect := []types.ReceiptStatus{types.RSFail, types.RSFail, types.RSBalanceNotEnough}
+	for i := range expect {
+		if transfers[i] != expect[i] {
+			t.Fatalf("unexpected transfer status %v", transfers)
+		}
+	}
+	// The applied transfers must be reverted
+	for _, item := range items {
+		if db.GetBalance(item.Target).Sign() != 0 {
+			t.Fatalf("transfer not reverted")
+		}
+	}
+	if db.GetBalance(source).Uint64() != 500 {
+		t.Fatalf("unexpected source balance %v", db.GetBalance(source))
+	}
+}
+
+func TestBatchTransfer_Validate(t *testing.T) {
+	source := common.BytesToAddress([]byte("payroll"))
+	tx, _ := newBatchTransferTx(t, source, 100, 200)
+	tx.GasLimit = types.NewBigInt(BatchTransferGas(2) - 1)
+	if err := validateBatchTransfer(tx); err == nil {
+		t.Fatalf("low gas limit should fail")
+	}
+
+	tx, _ = newBatchTransferTx(t, source, 100)
+	tx.Target = &source
+	if err := validateBatchTransfer(tx); err == nil {
+		t.Fatalf("target should not be set")
+	}
+
+	tx, _ = newBatchTransferTx(t, source, 100)
+	tx.Data = []byte("garbage")
+	if err := validateBatchTransfer(tx); err == nil {
+		t.Fatalf("garbage data should fail")
+	}
+	db := newTestAccountDB(t)
+	if status, _, gasUsed := executeBatchTransfer(db, tx); status != types.RSParseFail || gasUsed != BatchTransferBaseGas {
+		t.Fatalf("unexpected result of garbage data %v %v", status, gasUsed)
+	}
+}
@@ -0,0 +1,125 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/account"
+	"github.com/xchain/go-chain/storage/xchaindb"
+)
+
+const (
+	// StateDBPrefix is the prefix of the account state database of the chain
+	StateDBPrefix = "state"
+
+	// FlatStatePrefix is the prefix of the flat state database. It is separated from the state
+	// database, as the pruner deletes the keys not of the trie nodes from there
+	FlatStatePrefix = "flatstate"
+
+	flatStateDiffLayers = 128 // The number of the recent roots kept as the diff layers of the flat state
+)
+
+// ChainState is the account state store of the chain. The states of the added blocks are committed
+// through it, so the historical states are pruned in the configured gc mode, and the states of the
+// blocks are opened by the headers of the chain
+type ChainState struct {
+	chain  stateHeaderReader
+	db     account.AccountDatabase
+	pruner *statePruner
+	lock   sync.Mutex
+}
+
+// ChainStateImpl is the account state store of the running chain, opened by NewChainState when the
+// chain is initialised. It is nil if the chain isn't initialised
+var ChainStateImpl *ChainState
+
+// NewChainState opens the account state store of the chain in the data source. The flat state is
+// loaded at the state of the top block, or regenerated from its trie if it isn't of the top block
+func NewChainState(ds *xchaindb.XchainDataSource, chain stateHeaderReader) (*ChainState, error) {
+	db, err := ds.NewPrefixDatabase(StateDBPrefix)
+	if err != nil {
+		return nil, err
+	}
+	flatdb, err := ds.NewPrefixDatabase(FlatStatePrefix)
+	if err != nil {
+		return nil, err
+	}
+	var root common.Hash
+	if top := chain.QueryTopBlock(); top != nil {
+		root = top.StateTree
+	}
+	sdb, err := account.NewDatabaseWithSnapshots(db, flatdb, root, flatStateDiffLayers)
+	if err != nil {
+		return nil, err
+	}
+	return &ChainState{
+		chain:  chain,
+		db:     sdb,
+		pruner: newStatePruner(sdb.TrieDB()),
+	}, nil
+}
+
+// AccountDatabase returns the backing store of the account states
+func (s *ChainState) AccountDatabase() account.AccountDatabase {
+	return s.db
+}
+
+// Commit writes the state of the block at the height and returns its root. The state is written to
+// the disk directly in the archive mode, or kept in the memory until it falls out of the retention
+// window in the full gc mode
+func (s *ChainState) Commit(height uint64, state *account.AccountDB) (common.Hash, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	root, err := state.Commit(true)
+	if err != nil {
+		return root, err
+	}
+	if s.pruner == nil {
+		return root, s.db.TrieDB().Commit(root, false)
+	}
+	return root, s.pruner.commit(height, root)
+}
+
+// Close writes the states kept in the memory to the disk
+func (s *ChainState) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.pruner == nil {
+		return nil
+	}
+	return s.pruner.close()
+}
+
+// ImportState rebuilds the state from the snapshot into the state database of the chain in the data
+// source, which should be empty. The snapshot should be of the trusted state root, as the header in it
+// is only checked against the rebuilt state. The flat state is regenerated when the chain state is opened
+func ImportState(ds *xchaindb.XchainDataSource, r io.Reader, root common.Hash) (account.SnapshotHeader, int, error) {
+	db, err := ds.NewPrefixDatabase(StateDBPrefix)
+	if err != nil {
+		return account.SnapshotHeader{}, 0, err
+	}
+	header, accounts, err := account.ImportSnapshot(r, account.NewDatabase(db))
+	if err == nil && header.StateTree != root {
+		err = fmt.Errorf("snapshot state root %v doesn't match the trusted root %v", header.StateTree.Hex(), root.Hex())
+	}
+	return header, accounts, err
+}
@@ -0,0 +1,146 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/xchain/go-chain/global/types"
+)
+
+const (
+	DefaultOracleBlocks   = 20  // Number of the recent blocks sampled by the oracle
+	DefaultOracleMinPrice = 500 // The price floor of the suggestions
+
+	oracleSlowPercentile     = 25
+	oracleStandardPercentile = 50
+	oracleFastPercentile     = 90
+)
+
+var (
+	OracleBlocks   = DefaultOracleBlocks
+	OracleMinPrice = uint64(DefaultOracleMinPrice)
+)
+
+type oracleChainReader interface {
+	Height() uint64
+	QueryBlockByHeight(height uint64) *types.Block
+}
+
+// PendingPricer reports the pressure of the pending transactions in the pool
+type PendingPricer interface {
+	// PackingThreshold returns the lowest gas price packed into the next block
+	// if the pending transactions are more than a block can hold, otherwise returns 0
+	PackingThreshold(gasLimit uint64) uint64
+}
+
+// GasPriceSuggestion is the suggested gas prices for different confirmation speed
+type GasPriceSuggestion struct {
+	Slow     uint64 `json:"slow"`
+	Standard uint64 `json:"standard"`
+	Fast     uint64 `json:"fast"`
+}
+
+// GasPriceOracle suggests gas prices according to the prices included in the recent blocks
+// and the pending transactions in the pool
+type GasPriceOracle struct {
+	chain    oracleChainReader
+	pool     PendingPricer
+	blocks   int
+	minPrice uint64
+
+	lock        sync.Mutex
+	cacheHeight uint64
+	cachePrices []uint64 // Sorted prices sampled at the cache height
+}
+
+// NewGasPriceOracle creates the oracle sampling the given number of recent blocks, pool can be nil
+func NewGasPriceOracle(chain oracleChainReader, pool PendingPricer, blocks int, minPrice uint64) *GasPriceOracle {
+	if blocks <= 0 {
+		blocks = DefaultOracleBlocks
+	}
+	return &GasPriceOracle{
+		chain:    chain,
+		pool:     pool,
+		blocks:   blocks,
+		minPrice: minPrice,
+	}
+}
+
+// samplePrices collects the gas prices of the transactions in the recent blocks in ascending order.
+// The result is cached until a new block is added
+func (o *GasPriceOracle) samplePrices() []uint64 {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	top := o.chain.Height()
+	if o.cachePrices != nil && o.cacheHeight == top {
+		return o.cachePrices
+	}
+	prices := make([]uint64, 0)
+	for i := 0; i < o.blocks && uint64(i) <= top; i++ {
+		b := o.chain.QueryBlockByHeight(top - uint64(i))
+		if b == nil {
+			continue
+		}
+		for _, tx := range b.Transactions {
+			if tx.GasPrice == nil || tx.GasPrice.Uint64() == 0 {
+				continue
+			}
+			prices = append(prices, tx.GasPrice.Uint64())
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i] < prices[j]
+	})
+	o.cacheHeight = top
+	o.cachePrices = prices
+	return prices
+}
+
+func (o *GasPriceOracle) percentile(prices []uint64, p int) uint64 {
+	price := o.minPrice
+	if len(prices) > 0 && prices[(len(prices)-1)*p/100] > price {
+		price = prices[(len(prices)-1)*p/100]
+	}
+	return price
+}
+
+// Suggest returns the slow, standard and fast gas prices
+func (o *GasPriceOracle) Suggest() *GasPriceSuggestion {
+	prices := o.samplePrices()
+	s := &GasPriceSuggestion{
+		Slow:     o.percentile(prices, oracleSlowPercentile),
+		Standard: o.percentile(prices, oracleStandardPercentile),
+		Fast:     o.percentile(prices, oracleFastPercentile),
+	}
+	// The pool holds more than a block, the cheaper ones have to wait for later blocks
+	if o.pool != nil {
+		if threshold := o.pool.PackingThreshold(GasLimitForPackage); threshold > 0 {
+			if s.Standard < threshold {
+				s.Standard = threshold
+			}
+			if s.Fast <= threshold {
+				s.Fast = threshold + 1
+			}
+		}
+	}
+	if s.Slow > s.Standard {
+		s.Slow = s.Standard
+	}
+	return s
+}
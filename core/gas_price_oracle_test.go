@@ -0,0 +1,100 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+type mockOracleChain []*types.Block
+
+func (m mockOracleChain) Height() uint64 {
+	return uint64(len(m) - 1)
+}
+
+func (m mockOracleChain) QueryBlockByHeight(height uint64) *types.Block {
+	if height >= uint64(len(m)) {
+		return nil
+	}
+	return m[height]
+}
+
+func newMockOracleChain(blockPrices ...[]uint64) mockOracleChain {
+	source := common.BytesToAddress([]byte("1"))
+	chain := make(mockOracleChain, 0)
+	for _, prices := range blockPrices {
+		b := &types.Block{}
+		for i, price := range prices {
+			b.Transactions = append(b.Transactions, newMockTx(source, uint64(i+1), price))
+		}
+		chain = append(chain, b)
+	}
+	return chain
+}
+
+func TestGasPriceOracle_Percentiles(t *testing.T) {
+	chain := newMockOracleChain(
+		[]uint64{100, 200, 300, 400, 500},
+		[]uint64{600, 700, 800, 900, 1000},
+		[]uint64{1100},
+	)
+	oracle := NewGasPriceOracle(chain, nil, 20, 150)
+
+	s := oracle.Suggest()
+	if s.Slow != 300 || s.Standard != 600 || s.Fast != 1000 {
+		t.Fatalf("unexpected suggestion %+v", s)
+	}
+
+	// Only the last 2 blocks are sampled
+	oracle = NewGasPriceOracle(chain, nil, 2, 150)
+	if s := oracle.Suggest(); s.Slow != 700 || s.Standard != 800 || s.Fast != 1000 {
+		t.Fatalf("unexpected suggestion %+v", s)
+	}
+}
+
+func TestGasPriceOracle_MinPrice(t *testing.T) {
+	oracle := NewGasPriceOracle(newMockOracleChain([]uint64{}), nil, 20, 500)
+	if s := oracle.Suggest(); s.Slow != 500 || s.Standard != 500 || s.Fast != 500 {
+		t.Fatalf("unexpected suggestion %+v", s)
+	}
+}
+
+func TestGasPriceOracle_PoolPressure(t *testing.T) {
+	defer func(limit uint64) { GasLimitForPackage = limit }(GasLimitForPackage)
+	GasLimitForPackage = 3000 * 3
+
+	chain := newMockOracleChain([]uint64{100, 200, 300})
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	oracle := NewGasPriceOracle(chain, c, 20, 50)
+
+	if s := oracle.Suggest(); s.Standard != 200 || s.Fast != 200 {
+		t.Fatalf("unexpected suggestion %+v", s)
+	}
+
+	// The pool holds 4 transactions while a block can only pack 3 of them
+	for i, price := range []uint64{1000, 2000, 3000, 4000} {
+		c.push(newMockTx(common.BytesToAddress([]byte{byte(i)}), 1, price))
+	}
+	if threshold := c.PackingThreshold(GasLimitForPackage); threshold != 2000 {
+		t.Fatalf("unexpected packing threshold %v", threshold)
+	}
+	if s := oracle.Suggest(); s.Slow != 100 || s.Standard != 2000 || s.Fast != 2001 {
+		t.Fatalf("unexpected suggestion %+v", s)
+	}
+}
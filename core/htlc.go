@@ -0,0 +1,234 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+// htlcEscrowAddress is the account holding the locked value of all hash time-locked transfers,
+// the locks are stored in its data keyed by the hash lock and the sender. Each sender has its own lock of
+// the hash lock, so that locking the same hash lock ahead by others can't take the place of the lock,
+// and all the locks of a hash lock can be listed by the key prefix
+var htlcEscrowAddress = common.BytesToAddress(common.Sha256([]byte("htlc_escrow")))
+
+var htlcKeyPrefix = []byte("htlc")
+
+func htlcHashLockPrefix(hashLock common.Hash) []byte {
+	return append(common.CopyBytes(htlcKeyPrefix), hashLock.Bytes()...)
+}
+
+func htlcKey(sender common.Address, hashLock common.Hash) []byte {
+	return append(htlcHashLockPrefix(hashLock), sender.Bytes()...)
+}
+
+// GetHTLC returns all the hash time-locked transfers with the hash lock, one for each sender locked with it.
+// Locks are read from the committed state, so the ones changed in the current block are not listed
+func GetHTLC(db types.AccountDB, hashLock common.Hash) ([]*types.HTLC, error) {
+	locks := make([]*types.HTLC, 0)
+	prefix := htlcHashLockPrefix(hashLock)
+	iter := db.DataIterator(htlcEscrowAddress, prefix)
+	if iter == nil {
+		return locks, nil
+	}
+	for iter.Next() {
+		if !bytes.HasPrefix(iter.Key, prefix) {
+			break
+		}
+		h, err := types.DecodeHTLC(iter.Value)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, h)
+	}
+	return locks, nil
+}
+
+// getHTLC returns the hash time-locked transfer of the sender with the hash lock, or nil if not found
+func getHTLC(db types.AccountDB, sender common.Address, hashLock common.Hash) (*types.HTLC, error) {
+	data := db.GetData(htlcEscrowAddress, htlcKey(sender, hashLock))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return types.DecodeHTLC(data)
+}
+
+func putHTLC(db types.AccountDB, h *types.HTLC) error {
+	data, err := types.EncodeHTLC(h)
+	if err != nil {
+		return err
+	}
+	db.SetData(htlcEscrowAddress, htlcKey(h.Sender, h.HashLock), data)
+	return nil
+}
+
+// validateHTLC checks the hash time-locked transaction before it enters the pool.
+// The lock takes the target as the recipient and the value as the amount, the claim takes the target
+// as the sender of the lock and the preimage in the data, while the refund only carries the hash lock in the data
+func validateHTLC(tx *types.Transaction, height uint64) error {
+	switch tx.Type {
+	case types.TransactionTypeHTLCLock:
+		if tx.Target == nil {
+			return fmt.Errorf("htlc lock should have target")
+		}
+		if tx.Value == nil || tx.Value.Sign() <= 0 {
+			return fmt.Errorf("htlc lock should have positive value")
+		}
+		lock, err := types.DecodeHTLCLock(tx.Data)
+		if err != nil {
+			return err
+		}
+		if lock.Timeout <= height {
+			return fmt.Errorf("htlc timeout %v should be higher than current height %v", lock.Timeout, height)
+		}
+	case types.TransactionTypeHTLCClaim, types.TransactionTypeHTLCRefund:
+		if tx.Type == types.TransactionTypeHTLCClaim && tx.Target == nil {
+			return fmt.Errorf("htlc claim should have the lock sender as target")
+		}
+		if tx.Type == types.TransactionTypeHTLCRefund && tx.Target != nil {
+			return fmt.Errorf("htlc refund should not have target")
+		}
+		if tx.Value != nil && tx.Value.Sign() != 0 {
+			return fmt.Errorf("htlc claim or refund should not have value")
+		}
+		if tx.Type == types.TransactionTypeHTLCClaim && len(tx.Data) != types.HTLCPreimageLength {
+			return fmt.Errorf("preimage should be %v bytes, got %v", types.HTLCPreimageLength, len(tx.Data))
+		}
+		if tx.Type == types.TransactionTypeHTLCRefund && len(tx.Data) != common.HashLength {
+			return fmt.Errorf("hash lock should be %v bytes, got %v", common.HashLength, len(tx.Data))
+		}
+	default:
+		return fmt.Errorf("not a htlc transaction")
+	}
+	return nil
+}
+
+// executeHTLC applies the hash time-locked transaction at the given block height
+func executeHTLC(db types.AccountDB, tx *types.Transaction, height uint64) types.ReceiptStatus {
+	var err error
+	switch tx.Type {
+	case types.TransactionTypeHTLCLock:
+		return executeHTLCLock(db, tx, height)
+	case types.TransactionTypeHTLCClaim:
+		err = executeHTLCClaim(db, tx, height)
+	case types.TransactionTypeHTLCRefund:
+		err = executeHTLCRefund(db, tx, height)
+	default:
+		return types.RSFail
+	}
+	if err != nil {
+		logger.Debugf("htlc tx %v fail:%v", tx.Hash.Hex(), err)
+		return types.RSFail
+	}
+	return types.RSSuccess
+}
+
+func executeHTLCLock(db types.AccountDB, tx *types.Transaction, height uint64) types.ReceiptStatus {
+	lock, err := types.DecodeHTLCLock(tx.Data)
+	if err != nil {
+		logger.Debugf("htlc lock %v parse fail:%v", tx.Hash.Hex(), err)
+		return types.RSParseFail
+	}
+	if lock.Timeout <= height {
+		return types.RSFail
+	}
+	// The hash lock can't be reused by the sender even if the previous one is done, the preimage may be revealed already
+	if exist, err := getHTLC(db, *tx.Source, lock.HashLock); err != nil || exist != nil {
+		return types.RSFail
+	}
+	value := tx.Value.Value()
+	if !db.CanTransfer(*tx.Source, value) {
+		return types.RSBalanceNotEnough
+	}
+	h := &types.HTLC{
+		Sender:    *tx.Source,
+		Recipient: *tx.Target,
+		Amount:    tx.Value,
+		HashLock:  lock.HashLock,
+		Timeout:   lock.Timeout,
+		State:     types.HTLCLocked,
+	}
+	if !db.Exist(htlcEscrowAddress) {
+		db.CreateAccount(htlcEscrowAddress)
+	}
+	if err := putHTLC(db, h); err != nil {
+		return types.RSFail
+	}
+	db.Transfer(*tx.Source, htlcEscrowAddress, value)
+	return types.RSSuccess
+}
+
+// lockedHTLC returns the lock of the sender with the hash lock which is still waiting to be claimed or refunded
+func lockedHTLC(db types.AccountDB, sender common.Address, hashLock common.Hash) (*types.HTLC, error) {
+	h, err := getHTLC(db, sender, hashLock)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return nil, fmt.Errorf("htlc %v not found", hashLock.Hex())
+	}
+	if h.State != types.HTLCLocked {
+		return nil, fmt.Errorf("htlc %v is already %v", hashLock.Hex(), h.State)
+	}
+	return h, nil
+}
+
+func executeHTLCClaim(db types.AccountDB, tx *types.Transaction, height uint64) error {
+	preimage := tx.Data
+	h, err := lockedHTLC(db, *tx.Target, types.HTLCHashLock(preimage))
+	if err != nil {
+		return err
+	}
+	if height > h.Timeout {
+		return fmt.Errorf("htlc timeout at %v", h.Timeout)
+	}
+	if *tx.Source != h.Recipient {
+		return fmt.Errorf("only the recipient can claim")
+	}
+	if err := h.Unlocks(preimage); err != nil {
+		return err
+	}
+	h.State = types.HTLCClaimed
+	h.Preimage = common.CopyBytes(preimage)
+	if err := putHTLC(db, h); err != nil {
+		return err
+	}
+	db.Transfer(htlcEscrowAddress, h.Recipient, h.Amount.Value())
+	return nil
+}
+
+func executeHTLCRefund(db types.AccountDB, tx *types.Transaction, height uint64) error {
+	h, err := lockedHTLC(db, *tx.Source, common.BytesToHash(tx.Data))
+	if err != nil {
+		return err
+	}
+	if height <= h.Timeout {
+		return fmt.Errorf("htlc can't be refunded before %v", h.Timeout+1)
+	}
+	if *tx.Source != h.Sender {
+		return fmt.Errorf("only the sender can refund")
+	}
+	h.State = types.HTLCRefunded
+	if err := putHTLC(db, h); err != nil {
+		return err
+	}
+	db.Transfer(htlcEscrowAddress, h.Sender, h.Amount.Value())
+	return nil
+}
@@ -0,0 +1,196 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+var (
+	htlcSender    = common.BytesToAddress([]byte("htlc_sender"))
+	htlcRecipient = common.BytesToAddress([]byte("htlc_recipient"))
+	htlcPreimage  = common.Sha256([]byte("secret"))
+)
+
+func newHTLCTx(t *testing.T, typ int8, source common.Address, data []byte) *types.Transaction {
+	tx := newTypedTx(typ, source, 1, 500, func(tx *types.Transaction) {
+		tx.Data, tx.Target, tx.Value = data, nil, nil
+		switch typ {
+		case types.TransactionTypeHTLCLock:
+			tx.Target = &htlcRecipient
+			tx.Value = types.NewBigInt(1000)
+		case types.TransactionTypeHTLCClaim:
+			tx.Target = &htlcSender
+		}
+	})
+	if err := validateHTLC(tx, 10); err != nil {
+		t.Fatalf("validate error:%v", err)
+	}
+	return tx
+}
+
+func lockHTLC(t *testing.T, db types.AccountDB, timeout uint64) common.Hash {
+	hashLock := types.HTLCHashLock(htlcPreimage)
+	data, err := types.EncodeHTLCLock(&types.HTLCLockData{HashLock: hashLock, Timeout: timeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AddBalance(htlcSender, big.NewInt(5000))
+	tx := newHTLCTx(t, types.TransactionTypeHTLCLock, htlcSender, data)
+	if status := executeHTLC(db, tx, 10); status != types.RSSuccess {
+		t.Fatalf("lock fail:%v", status)
+	}
+	// The hash lock can't be used twice
+	if status := executeHTLC(db, tx, 10); status != types.RSFail {
+		t.Fatalf("hash lock reused")
+	}
+	if db.GetBalance(htlcSender).Uint64() != 4000 {
+		t.Fatalf("unexpected sender balance %v", db.GetBalance(htlcSender))
+	}
+	return hashLock
+}
+
+func checkHTLCState(t *testing.T, db types.AccountDB, hashLock common.Hash, state types.HTLCState) *types.HTLC {
+	h, err := getHTLC(db, htlcSender, hashLock)
+	if err != nil || h == nil {
+		t.Fatalf("htlc not found:%v", err)
+	}
+	if h.State != state {
+		t.Fatalf("unexpected state %v, expect %v", h.State, state)
+	}
+	return h
+}
+
+func TestHTLC_Validate(t *testing.T) {
+	lockData, _ := types.EncodeHTLCLock(&types.HTLCLockData{HashLock: types.HTLCHashLock(htlcPreimage), Timeout: 10})
+	invalid := map[string]*types.Transaction{
+		"lock without target":   {Type: types.TransactionTypeHTLCLock, Value: types.NewBigInt(1), Data: lockData},
+		"lock without value":    {Type: types.TransactionTypeHTLCLock, Target: &htlcRecipient, Data: lockData},
+		"lock timed out":        {Type: types.TransactionTypeHTLCLock, Target: &htlcRecipient, Value: types.NewBigInt(1), Data: lockData},
+		"claim with short data": {Type: types.TransactionTypeHTLCClaim, Target: &htlcSender, Data: []byte("secret")},
+		"claim without target":  {Type: types.TransactionTypeHTLCClaim, Data: htlcPreimage},
+		"refund with target":    {Type: types.TransactionTypeHTLCRefund, Target: &htlcSender, Data: htlcPreimage},
+		"refund with value":     {Type: types.TransactionTypeHTLCRefund, Value: types.NewBigInt(1), Data: htlcPreimage},
+		"not htlc":              {Type: types.TransactionTypeTransfer},
+	}
+	for name, tx := range invalid {
+		if err := validateHTLC(tx, 10); err == nil {
+			t.Errorf("%v: should be invalid", name)
+		}
+	}
+}
+
+func TestHTLC_Claim(t *testing.T) {
+	db := newTestAccountDB(t)
+	hashLock := lockHTLC(t, db, 20)
+
+	wrong := common.Sha256([]byte("wrong"))
+	if status := executeHTLC(db, newHTLCTx(t, types.TransactionTypeHTLCClaim, htlcRecipient, wrong), 15); status != types.RSFail {
+		t.Fatalf("claimed with wrong preimage")
+	}
+	if status := executeHTLC(db, newHTLCTx(t, types.TransactionTypeHTLCClaim, htlcSender, htlcPreimage), 15); status != types.RSFail {
+		t.Fatalf("claimed by non recipient")
+	}
+	if status := executeHTLC(db, newHTLCTx(t, types.TransactionTypeHTLCClaim, htlcRecipient, htlcPreimage), 21); status != types.RSFail {
+		t.Fatalf("claimed after timeout")
+	}
+	if status := executeHTLC(db, newHTLCTx(t, types.TransactionTypeHTLCRefund, htlcSender, hashLock.Bytes()), 20); status != types.RSFail {
+		t.Fatalf("refunded before timeout")
+	}
+
+	claim := newHTLCTx(t, types.TransactionTypeHTLCClaim, htlcRecipient, htlcPreimage)
+	if status := executeHTLC(db, claim, 20); status != types.RSSuccess {
+		t.Fatalf("claim fail:%v", status)
+	}
+	if db.GetBalance(htlcRecipient).Uint64() != 1000 {
+		t.Fatalf("unexpected recipient balance %v", db.GetBalance(htlcRecipient))
+	}
+	h := checkHTLCState(t, db, hashLock, types.HTLCClaimed)
+	if string(h.Preimage) != string(htlcPreimage) {
+		t.Fatalf("preimage not revealed")
+	}
+	if status := executeHTLC(db, claim, 20); status != types.RSFail {
+		t.Fatalf("claimed twice")
+	}
+	if status := executeHTLC(db, newHTLCTx(t, types.TransactionTypeHTLCRefund, htlcSender, hashLock.Bytes()), 21); status != types.RSFail {
+		t.Fatalf("refunded after claimed")
+	}
+}
+
+func TestHTLC_Refund(t *testing.T) {
+	db := newTestAccountDB(t)
+	hashLock := lockHTLC(t, db, 20)
+
+	if status := executeHTLC(db, newHTLCTx(t, types.TransactionTypeHTLCRefund, htlcRecipient, hashLock.Bytes()), 21); status != types.RSFail {
+		t.Fatalf("refunded by non sender")
+	}
+	if status := executeHTLC(db, newHTLCTx(t, types.TransactionTypeHTLCRefund, htlcSender, hashLock.Bytes()), 21); status != types.RSSuccess {
+		t.Fatalf("refund fail:%v", status)
+	}
+	if db.GetBalance(htlcSender).Uint64() != 5000 {
+		t.Fatalf("unexpected sender balance %v", db.GetBalance(htlcSender))
+	}
+	checkHTLCState(t, db, hashLock, types.HTLCRefunded)
+	if status := executeHTLC(db, newHTLCTx(t, types.TransactionTypeHTLCClaim, htlcRecipient, htlcPreimage), 21); status != types.RSFail {
+		t.Fatalf("claimed after refunded")
+	}
+}
+
+func TestHTLC_LockedAheadByOthers(t *testing.T) {
+	db := newTestAccountDB(t)
+	// Someone sees the hash lock and locks a tiny amount with it first
+	attacker := common.BytesToAddress([]byte("htlc_attacker"))
+	db.AddBalance(attacker, big.NewInt(1))
+	data, _ := types.EncodeHTLCLock(&types.HTLCLockData{HashLock: types.HTLCHashLock(htlcPreimage), Timeout: 20})
+	ahead := newHTLCTx(t, types.TransactionTypeHTLCLock, attacker, data)
+	ahead.Value = types.NewBigInt(1)
+	if status := executeHTLC(db, ahead, 10); status != types.RSSuccess {
+		t.Fatalf("lock fail:%v", status)
+	}
+
+	// The lock of the sender still goes through and is claimed from the sender
+	hashLock := lockHTLC(t, db, 20)
+	if status := executeHTLC(db, newHTLCTx(t, types.TransactionTypeHTLCClaim, htlcRecipient, htlcPreimage), 15); status != types.RSSuccess {
+		t.Fatalf("claim fail:%v", status)
+	}
+	if db.GetBalance(htlcRecipient).Uint64() != 1000 {
+		t.Fatalf("unexpected recipient balance %v", db.GetBalance(htlcRecipient))
+	}
+	checkHTLCState(t, db, hashLock, types.HTLCClaimed)
+	if h, _ := getHTLC(db, attacker, hashLock); h == nil || h.State != types.HTLCLocked {
+		t.Fatalf("lock of others should be untouched")
+	}
+
+	// Both locks are found by the hash lock alone
+	db = commitTestAccountDB(t, db)
+	locks, err := GetHTLC(db, hashLock)
+	if err != nil || len(locks) != 2 {
+		t.Fatalf("unexpected locks of the hash lock %v:%v", len(locks), err)
+	}
+	states := map[common.Address]types.HTLCState{htlcSender: types.HTLCClaimed, attacker: types.HTLCLocked}
+	for _, h := range locks {
+		if state, ok := states[h.Sender]; !ok || h.HashLock != hashLock || h.State != state {
+			t.Fatalf("unexpected lock of %v: %v", h.Sender.Hex(), h.State)
+		}
+	}
+	if locks, _ := GetHTLC(db, types.HTLCHashLock([]byte("other"))); len(locks) != 0 {
+		t.Fatalf("locks of other hash lock listed")
+	}
+}
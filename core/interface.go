@@ -40,7 +40,8 @@ type blockSyncI interface {
 }
 
 type txPoolI interface {
-	// PackForCast returns a list of transactions for casting a block
+	// PackForCast returns a list of transactions for casting a block, selected by the pending container
+	// with the configured packing strategy and limits
 	PackForCast() []*types.Transaction
 
 	// GetTransaction trys to find a transaction from pool by hash and return it
@@ -48,6 +49,10 @@ type txPoolI interface {
 
 	GetReceipt(hash common.Hash) *types.Receipt
 
+	// AddLocalTransaction adds the transaction submitted through the rpc of this node. Local transactions are
+	// journaled, exempted from the eviction and rebroadcast until included
+	AddLocalTransaction(tx *types.Transaction) (bool, error)
+
 	// RemoveFromPool removes the transactions from pool by hash
 	RemoveFromPool(txs []common.Hash)
 
@@ -60,6 +65,22 @@ type txPoolI interface {
 	SaveReceipts(blockHash common.Hash, receipts types.Receipts) error
 
 	DeleteReceipts(txs []common.Hash) error
+
+	// PackingThreshold returns the lowest gas price packed into the next block if the pending transactions
+	// are more than a block can hold, otherwise returns 0. It is the pool pressure used by the gas price oracle
+	PackingThreshold(gasLimit uint64) uint64
+
+	// Status returns the statistics of the pool
+	Status() *TxPoolStatus
+
+	// Content returns all the transactions in the pool grouped by source and ordered by nonce
+	Content() (pending, queued map[common.Address][]*types.Transaction)
+
+	// ContentFrom returns the transactions of the given source in the pool ordered by nonce
+	ContentFrom(source common.Address) (pending, queued []*types.Transaction)
+
+	// Inspect finds out where the transaction is in the pool, and the reasons if it is queued
+	Inspect(hash common.Hash) *TxInspection
 }
 
 type umidStore interface {
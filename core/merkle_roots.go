@@ -0,0 +1,59 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/xchain/go-chain/global/types"
+)
+
+// MerkleRootsForkHeight is the height of the first block whose TxTree and ReceiptTree are the merkle roots
+// of types.CalcTxTree and types.CalcReceiptTree, so that the transactions and receipts can be proved against
+// the header. The blocks before it keep the trees they were built with. It's disabled unless configured
+var MerkleRootsForkHeight uint64 = math.MaxUint64
+
+// MerkleRootsActivated reports whether the block of the given height commits to the merkle roots
+func MerkleRootsActivated(height uint64) bool {
+	return height >= MerkleRootsForkHeight
+}
+
+// SetMerkleRoots sets the merkle roots of the transactions and receipts to the header of the block being cast.
+// It returns false before the fork height, and the header should be given the legacy trees
+func SetMerkleRoots(bh *types.BlockHeader, txs []*types.Transaction, receipts types.Receipts) bool {
+	if !MerkleRootsActivated(bh.Height) {
+		return false
+	}
+	bh.TxTree = types.CalcTxTree(txs)
+	bh.ReceiptTree = types.CalcReceiptTree(receipts)
+	return true
+}
+
+// VerifyMerkleRoots checks the header of the block being verified commits to the merkle roots of the transactions
+// and the receipts from the fork height. It returns false before the fork height, and the legacy trees should be checked
+func VerifyMerkleRoots(bh *types.BlockHeader, txs []*types.Transaction, receipts types.Receipts) (bool, error) {
+	if !MerkleRootsActivated(bh.Height) {
+		return false, nil
+	}
+	if root := types.CalcTxTree(txs); root != bh.TxTree {
+		return true, fmt.Errorf("tx tree mismatch, expect %v, got %v", root.Hex(), bh.TxTree.Hex())
+	}
+	if root := types.CalcReceiptTree(receipts); root != bh.ReceiptTree {
+		return true, fmt.Errorf("receipt tree mismatch, expect %v, got %v", root.Hex(), bh.ReceiptTree.Hex())
+	}
+	return true, nil
+}
@@ -0,0 +1,67 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+func TestMerkleRoots_Fork(t *testing.T) {
+	if MerkleRootsActivated(1 << 40) {
+		t.Fatalf("merkle roots should be disabled unless configured")
+	}
+	defer func(h uint64) { MerkleRootsForkHeight = h }(MerkleRootsForkHeight)
+	MerkleRootsForkHeight = 10
+
+	source := common.BytesToAddress([]byte("merkle"))
+	txs := []*types.Transaction{newMockTx(source, 1, 1000), newMockTx(source, 2, 1000), newMockTx(source, 3, 1000)}
+	receipts := make(types.Receipts, len(txs))
+	for i, tx := range txs {
+		receipts[i] = &types.Receipt{TxHash: tx.Hash, Status: types.RSSuccess, Height: 10, TxIndex: uint16(i)}
+	}
+
+	legacy := &types.BlockHeader{Height: 9, TxTree: common.BytesToHash([]byte("legacy"))}
+	if SetMerkleRoots(legacy, txs, receipts) || legacy.TxTree != common.BytesToHash([]byte("legacy")) {
+		t.Fatalf("legacy trees should be kept before fork")
+	}
+	if activated, err := VerifyMerkleRoots(legacy, txs, receipts); activated || err != nil {
+		t.Fatalf("legacy trees should not be checked, %v", err)
+	}
+
+	bh := &types.BlockHeader{Height: 10, CumulativeDifficulty: big.NewInt(1)}
+	if !SetMerkleRoots(bh, txs, receipts) {
+		t.Fatalf("merkle roots should be set from fork")
+	}
+	if _, err := VerifyMerkleRoots(bh, txs, receipts); err != nil {
+		t.Fatalf("verify error:%v", err)
+	}
+	// The transaction can be proved against the header
+	bh.Hash = bh.GenHash()
+	proof, err := types.NewTxProof(txs, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := types.VerifyTxProof(bh, txs[1].Hash, proof); err != nil {
+		t.Fatalf("verify proof error:%v", err)
+	}
+	if _, err := VerifyMerkleRoots(bh, txs[:2], receipts); err == nil {
+		t.Fatalf("tx tree mismatch should be found")
+	}
+}
@@ -0,0 +1,127 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+// multisigPolicyKey is the data key of the policy stored in the multisig account
+var multisigPolicyKey = []byte("multisig_policy")
+
+// multisigPolicy returns the policy of the account, or nil if it's not a multisig account
+func multisigPolicy(db types.AccountDB, addr common.Address) (*types.MultisigPolicy, error) {
+	data := db.GetData(addr, multisigPolicyKey)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return types.DecodeMultisigPolicy(data)
+}
+
+// validateMultisigCreate checks the multisig creating transaction before it enters the pool
+func validateMultisigCreate(tx *types.Transaction) error {
+	if tx.Target != nil {
+		return fmt.Errorf("multisig creating should not have target, the account address is derived from the creator")
+	}
+	if tx.MultiSign != nil {
+		return fmt.Errorf("multisig account can't create another one")
+	}
+	_, err := types.DecodeMultisigPolicy(tx.Data)
+	return err
+}
+
+// accountInUse checks if the account has code or data. The account only holding balance is taken over by the
+// multisig creating with the balance kept, so that funding the address in advance can't block the creating
+func accountInUse(db types.AccountDB, addr common.Address) bool {
+	if db.GetCodeSize(addr) > 0 || len(db.GetData(addr, multisigPolicyKey)) > 0 {
+		return true
+	}
+	iter := db.DataIterator(addr, nil)
+	return iter != nil && iter.Next()
+}
+
+// executeMultisigCreate creates the multisig account with the policy in the transaction data,
+// and funds it with the value of the transaction
+func executeMultisigCreate(db types.AccountDB, tx *types.Transaction) (status types.ReceiptStatus, addr common.Address) {
+	addr = types.MultisigAddress(*tx.Source, tx.Hash)
+	policy, err := types.DecodeMultisigPolicy(tx.Data)
+	if err != nil {
+		logger.Debugf("multisig create %v parse fail:%v", tx.Hash.Hex(), err)
+		return types.RSParseFail, addr
+	}
+	if accountInUse(db, addr) {
+		return types.RSFail, addr
+	}
+	value := tx.Value.Value()
+	if !db.CanTransfer(*tx.Source, value) {
+		return types.RSBalanceNotEnough, addr
+	}
+	// Encoded again so that only the checked fields are stored
+	data, err := types.EncodeMultisigPolicy(policy)
+	if err != nil {
+		return types.RSParseFail, addr
+	}
+	db.CreateAccount(addr)
+	db.SetData(addr, multisigPolicyKey, data)
+	db.Transfer(*tx.Source, addr, value)
+	return types.RSSuccess, addr
+}
+
+// recoverMultisigSource verifies the signatures of the multisig transaction against the policy
+// of the sending account, and sets the account as the source if authorized
+func recoverMultisigSource(db types.AccountDB, tx *types.Transaction) error {
+	if tx.MultiSign == nil {
+		return fmt.Errorf("not a multisig transaction")
+	}
+	if tx.Sign != nil {
+		return fmt.Errorf("multisig transaction should not have single sign")
+	}
+	if tx.Type == types.TransactionTypeMultisigCreate {
+		return fmt.Errorf("multisig account can't create another one")
+	}
+	account := tx.MultiSign.Account
+	policy, err := multisigPolicy(db, account)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return fmt.Errorf("%v is not a multisig account", account.Hex())
+	}
+	if len(tx.MultiSign.Signs) > len(policy.PubKeys) {
+		return fmt.Errorf("too many signatures, %v of %v owners", len(tx.MultiSign.Signs), len(policy.PubKeys))
+	}
+	signers, err := tx.RecoverSigners()
+	if err != nil {
+		return err
+	}
+	if err := policy.Authorized(signers); err != nil {
+		return err
+	}
+	tx.Source = &account
+	return nil
+}
+
+// RecoverTxSource recovers the source of the transaction from the single sign,
+// or from the multi sign verified against the policy of the multisig account in the given state
+func RecoverTxSource(db types.AccountDB, tx *types.Transaction) error {
+	if tx.MultiSign != nil {
+		return recoverMultisigSource(db, tx)
+	}
+	return tx.RecoverSource()
+}
@@ -0,0 +1,165 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/crypto"
+	"github.com/xchain/go-chain/global/types"
+)
+
+func newMultisigOwners(n int) []crypto.PrivateKey {
+	keys := make([]crypto.PrivateKey, n)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey("")
+	}
+	return keys
+}
+
+func newMultisigCreateTx(t *testing.T, db types.AccountDB, keys []crypto.PrivateKey, threshold uint8, nonce uint64) *types.Transaction {
+	policy := &types.MultisigPolicy{Threshold: threshold}
+	for _, sk := range keys {
+		policy.PubKeys = append(policy.PubKeys, sk.GetPubKey().Bytes())
+	}
+	data, err := types.EncodeMultisigPolicy(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creator := common.BytesToAddress([]byte("treasury"))
+	db.AddBalance(creator, big.NewInt(10000))
+	tx := &types.Transaction{
+		Data:     data,
+		Value:    types.NewBigInt(5000),
+		Nonce:    nonce,
+		Type:     types.TransactionTypeMultisigCreate,
+		GasLimit: types.NewBigInt(3000),
+		GasPrice: types.NewBigInt(500),
+		Source:   &creator,
+	}
+	tx.Hash = tx.GenHash()
+	if err := validateMultisigCreate(tx); err != nil {
+		t.Fatalf("validate error:%v", err)
+	}
+	return tx
+}
+
+func createMultisigAccount(t *testing.T, db types.AccountDB, keys []crypto.PrivateKey, threshold uint8, nonce uint64) common.Address {
+	tx := newMultisigCreateTx(t, db, keys, threshold, nonce)
+	status, addr := executeMultisigCreate(db, tx)
+	if status != types.RSSuccess {
+		t.Fatalf("create multisig account fail:%v", status)
+	}
+	// The same creating transaction can't override the account
+	if status, _ := executeMultisigCreate(db, tx); status != types.RSFail {
+		t.Fatalf("multisig account overridden")
+	}
+	return addr
+}
+
+func newMultisigTx(account common.Address, signers ...crypto.PrivateKey) *types.Transaction {
+	target := common.BytesToAddress([]byte("receiver"))
+	tx := &types.Transaction{
+		Value:     types.NewBigInt(100),
+		Nonce:     1,
+		Target:    &target,
+		GasLimit:  types.NewBigInt(3000),
+		GasPrice:  types.NewBigInt(500),
+		MultiSign: &types.MultiSign{Account: account},
+		ChainID:   testChainID,
+	}
+	tx.Hash = tx.GenHash()
+	for _, sk := range signers {
+		sign, _ := sk.Sign(tx.Hash.Bytes())
+		tx.MultiSign.Signs = append(tx.MultiSign.Signs, &sign)
+	}
+	return tx
+}
+
+func TestMultisig_Create(t *testing.T) {
+	db := newTestAccountDB(t)
+	keys := newMultisigOwners(3)
+	addr := createMultisigAccount(t, db, keys, 2, 1)
+
+	if db.GetBalance(addr).Uint64() != 5000 {
+		t.Fatalf("multisig account not funded")
+	}
+	policy, err := multisigPolicy(db, addr)
+	if err != nil || policy == nil || policy.Threshold != 2 || len(policy.PubKeys) != 3 {
+		t.Fatalf("unexpected policy %v %v", policy, err)
+	}
+	if policy, _ := multisigPolicy(db, common.BytesToAddress([]byte("treasury"))); policy != nil {
+		t.Fatalf("normal account should have no policy")
+	}
+}
+
+func TestMultisig_CreatePrefunded(t *testing.T) {
+	db := newTestAccountDB(t)
+	tx := newMultisigCreateTx(t, db, newMultisigOwners(2), 2, 1)
+	// Funding the address in advance doesn't block the creating
+	addr := types.MultisigAddress(*tx.Source, tx.Hash)
+	db.AddBalance(addr, big.NewInt(700))
+	if status, created := executeMultisigCreate(db, tx); status != types.RSSuccess || created != addr {
+		t.Fatalf("create multisig account fail:%v", status)
+	}
+	if db.GetBalance(addr).Uint64() != 5700 {
+		t.Fatalf("unexpected balance %v", db.GetBalance(addr))
+	}
+	// The address depends on the creator as well
+	other := common.BytesToAddress([]byte("other"))
+	if types.MultisigAddress(other, tx.Hash) == addr {
+		t.Fatalf("address should depend on the creator")
+	}
+}
+
+func TestMultisig_Authorize(t *testing.T) {
+	db := newTestAccountDB(t)
+	keys := newMultisigOwners(3)
+	addr := createMultisigAccount(t, db, keys, 2, 1)
+
+	tx := newMultisigTx(addr, keys[0], keys[2])
+	if err := RecoverTxSource(db, tx); err != nil {
+		t.Fatalf("authorized tx rejected:%v", err)
+	}
+	if *tx.Source != addr {
+		t.Fatalf("source should be the multisig account")
+	}
+
+	stranger, _ := crypto.GenerateKey("")
+	cases := map[string]*types.Transaction{
+		"below threshold":    newMultisigTx(addr, keys[1]),
+		"duplicate signer":   newMultisigTx(addr, keys[1], keys[1]),
+		"not owner":          newMultisigTx(addr, keys[0], stranger),
+		"not multisig":       newMultisigTx(common.BytesToAddress([]byte("receiver")), keys[0], keys[1]),
+		"too many signature": newMultisigTx(addr, keys[0], keys[1], keys[2], keys[0]),
+	}
+	for name, tx := range cases {
+		if err := RecoverTxSource(db, tx); err == nil {
+			t.Fatalf("%v: should be rejected", name)
+		}
+	}
+
+	// Signatures for one multisig account can't be used for another one with the same owners
+	other := createMultisigAccount(t, db, keys, 2, 2)
+	tx = newMultisigTx(addr, keys[0], keys[1])
+	tx.MultiSign.Account = other
+	tx.Hash = tx.GenHash()
+	if err := RecoverTxSource(db, tx); err == nil {
+		t.Fatalf("signatures replayed to another account")
+	}
+}
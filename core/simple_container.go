@@ -17,29 +17,138 @@ package core
 
 import (
 	"container/heap"
+	"fmt"
+	"math/big"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	datacommon "github.com/Workiva/go-datastructures/common"
 	"github.com/Workiva/go-datastructures/slice/skip"
 
 	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global"
 	"github.com/xchain/go-chain/global/types"
+	"github.com/xchain/go-chain/middleware/notify"
+	"github.com/xchain/go-chain/middleware/ticker"
+	"github.com/xchain/go-chain/storage/xchaindb"
 )
 
-type nonceReader interface {
-	GetNonce(address common.Address) uint64
+// DefaultTxPriceBump is the default value of TxPriceBump
+const DefaultTxPriceBump = 10
+
+// TxPriceBump is the minimum gas price bump percentage required to replace a pool transaction with the same nonce
+var TxPriceBump uint64 = DefaultTxPriceBump
+
+// DefaultTxLifetime is the default value of TxLifetime
+const DefaultTxLifetime = 3 * time.Hour
+
+// TxLifetime is the max duration a transaction can stay in the pool since it is first seen
+var TxLifetime = DefaultTxLifetime
+
+const (
+	txExpireRoutine  = "tx_expire"
+	txExpireInterval = 60 // Sweep the expired transactions every minute
+)
+
+// txShardCount is the number of shards the pool transactions are split into by source
+const txShardCount = 16
+
+// queueLimitPerAccount is the max number of future transactions a single source can hold in the queue
+const queueLimitPerAccount = 128
+
+var (
+	errPendingFull      = fmt.Errorf("tx pending list is full")
+	errQueueFull        = fmt.Errorf("tx queue is full")
+	errAccountQueueFull = fmt.Errorf("tx queue slots of the source are used up")
+	errTxTypeNotSupport = fmt.Errorf("tx type not supported by the executor")
+
+	errTxMultiSignNotSupport = fmt.Errorf("multi-signed tx not supported by the executor")
+	errTxSponsorNotSupport   = fmt.Errorf("sponsored tx not supported by the executor")
+)
+
+// checkExecutable checks if the executor handles the transaction. The batch transfer, multisig, htlc and
+// stake delegation types, and the multi-signed and sponsored transactions are rejected until the executor handles them
+func checkExecutable(tx *types.Transaction) error {
+	switch tx.Type {
+	case types.TransactionTypeTransfer, types.TransactionTypeBindUMID, types.TransactionTypeTransformUMID,
+		types.TransactionTypeUnbindUMID, types.TransactionTypeStakeAdd, types.TransactionTypeStakeReduce:
+	default:
+		return errTxTypeNotSupport
+	}
+	if tx.MultiSign != nil {
+		return errTxMultiSignNotSupport
+	}
+	if tx.Sponsor != nil {
+		return errTxSponsorNotSupport
+	}
+	return nil
+}
+
+// ReplaceUnderpricedError is returned if a transaction tries to replace the pool one with the same nonce
+// without the required gas price bump
+type ReplaceUnderpricedError struct {
+	Exist       common.Hash // Hash of the transaction in the pool
+	MinGasPrice *big.Int    // The minimum gas price required to replace it
+}
+
+func (e *ReplaceUnderpricedError) Error() string {
+	return fmt.Sprintf("replacement tx underpriced, %v%% bump is required, gas price should be at least %v to replace %v", TxPriceBump, e.MinGasPrice, e.Exist.Hex())
+}
+
+// checkPriceBump checks whether tx pays enough to replace the exist one
+func checkPriceBump(exist *types.Transaction, tx *types.Transaction) error {
+	minPrice := new(big.Int).Mul(exist.GasPrice.Value(), big.NewInt(int64(100+TxPriceBump)))
+	minPrice.Div(minPrice, big.NewInt(100))
+	if minPrice.Cmp(exist.GasPrice.Value()) <= 0 {
+		minPrice.Add(exist.GasPrice.Value(), big.NewInt(1))
+	}
+	if tx.GasPrice.Cmp(minPrice) < 0 {
+		return &ReplaceUnderpricedError{Exist: exist.Hash, MinGasPrice: minPrice}
+	}
+	return nil
+}
+
+// poolChain is the chain the pool validates the transactions against
+type poolChain interface {
+	types.AccountRepository
+	types.LatestDBGetter
+	Height() uint64
 }
 
 type simpleContainer struct {
-	txsMap     map[common.Hash]*types.Transaction
-	nonceR      nonceReader
-	pending    *pendingContainer
-	queue      map[common.Hash]*types.Transaction
-	queueLimit int
+	pendingSize int64 // Total number of transactions in the pending lists of all shards, accessed atomically
+	queueSize   int64 // Total number of transactions in the queues of all shards, accessed atomically
+
+	pendingLimit int
+	queueLimit   int
+
+	chain   poolChain
+	shards  [txShardCount]*txShard // Transactions are sharded by source, so that different sources won't block each other
+	lookup  *txLookup              // Index of all the transactions by hash
+	journal *txJournal             // Journal of the locally submitted transactions, nil if not enabled
+	locals  *txLocals              // Transactions submitted through the rpc of this node
+
+	expiredPending uint64 // Total number of transactions expired in pending list, accessed atomically
+	expiredQueued  uint64 // Total number of transactions expired in queue, accessed atomically
+
+	outOfWindowPending uint64 // Total number of transactions pruned from pending list out of validity window, accessed atomically
+	outOfWindowQueued  uint64 // Total number of transactions pruned from queue out of validity window, accessed atomically
+
+	height uint64 // Height of the latest block on chain, accessed atomically
 
+	// lock is held for reading to operate on any single shard, and for writing to operate across the shards,
+	// e.g. evicting the lowest price transactions and sweeping the expired ones
 	lock sync.RWMutex
 }
 
+// txShard holds the pending and queued transactions of the sources mapped to it
+type txShard struct {
+	lock    sync.Mutex
+	pending *pendingContainer
+	queue   *queueContainer
+}
+
 type orderByNonceTx struct {
 	item *types.Transaction
 }
@@ -61,42 +170,54 @@ func (tx *orderByNonceTx) Compare(e datacommon.Comparator) int {
 	return 0
 }
 
-type priceHeap []*types.Transaction
+// packingHeap orders the transactions by the less function of the packing strategy
+type packingHeap struct {
+	txs  []*types.Transaction
+	less func(a, b *types.Transaction) bool
+}
 
-func (h priceHeap) Len() int           { return len(h) }
-func (h priceHeap) Less(i, j int) bool { return h[i].GasPrice.Cmp(h[j].GasPrice.Value()) > 0 }
-func (h priceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h packingHeap) Len() int           { return len(h.txs) }
+func (h packingHeap) Less(i, j int) bool { return h.less(h.txs[i], h.txs[j]) }
+func (h packingHeap) Swap(i, j int)      { h.txs[i], h.txs[j] = h.txs[j], h.txs[i] }
 
-func (h *priceHeap) Push(x interface{}) {
-	*h = append(*h, x.(*types.Transaction))
+func (h *packingHeap) Push(x interface{}) {
+	h.txs = append(h.txs, x.(*types.Transaction))
 }
 
-func (h *priceHeap) Pop() interface{} {
-	old := *h
+func (h *packingHeap) Pop() interface{} {
+	old := h.txs
 	n := len(old)
 	x := old[n-1]
-	*h = old[0 : n-1]
+	h.txs = old[0 : n-1]
 	return x
 }
 
 type pendingContainer struct {
-	limit int
-	size  int
+	size int
 
 	waitingMap map[common.Address]*skip.SkipList //*orderByNonceTx. Map of transactions group by source for waiting
 }
 
-// push the transaction into the pending list. tx which returns false will push to the queue
-func (s *pendingContainer) push(tx *types.Transaction, stateNonce uint64) bool {
-	var doInsertOrReplace = func() {
+// push the transaction into the pending list. tx which returns false will push to the queue.
+// It returns the transaction replaced by tx, and error if the transaction is rejected.
+// The pending list may exceed the limit after push, it is up to the container to evict the lowest price ones
+func (s *pendingContainer) push(tx *types.Transaction, stateNonce uint64) (dropped []*types.Transaction, success bool, err error) {
+	var doInsertOrReplace = func() error {
 		newTxNode := newOrderByNonceTx(tx)
 		existSource := s.waitingMap[*tx.Source].Get(newTxNode)[0]
-		if existSource != nil && existSource.(*orderByNonceTx).item.GasPrice.Cmp(tx.GasPrice.Value()) < 0 {
-			s.size--
-			s.waitingMap[*tx.Source].Delete(existSource)
+		if existSource != nil {
+			existTx := existSource.(*orderByNonceTx).item
+			if err := checkPriceBump(existTx, tx); err != nil {
+				return err
+			}
+			// Insert overwrites the exist one with the same nonce
+			s.waitingMap[*tx.Source].Insert(newTxNode)
+			dropped = append(dropped, existTx)
+			return nil
 		}
 		s.size++
 		s.waitingMap[*tx.Source].Insert(newTxNode)
+		return nil
 	}
 
 	if tx.Nonce == stateNonce+1 {
@@ -104,103 +225,147 @@ func (s *pendingContainer) push(tx *types.Transaction, stateNonce uint64) bool {
 			s.waitingMap[*tx.Source] = skip.New(uint16(16))
 		}
 
-		doInsertOrReplace()
+		if err = doInsertOrReplace(); err != nil {
+			return
+		}
 	} else {
 		if s.waitingMap[*tx.Source] == nil {
-			return false
+			return
 		}
 		bigNonceTx := skipGetLast(s.waitingMap[*tx.Source])
 		if bigNonceTx != nil {
 			bigNonce := bigNonceTx.(*orderByNonceTx).item.Nonce
 			if tx.Nonce > bigNonce+1 {
-				return false
+				return
 			}
 
-			doInsertOrReplace()
+			if err = doInsertOrReplace(); err != nil {
+				return
+			}
 		}
 	}
 
-	//remove lowest price transaction if pending is full
-	var lowPriceTx *types.Transaction
-	if s.size >= s.limit {
-		for _, sourcedMap := range s.waitingMap {
-			lastTx := skipGetLast(sourcedMap).(*orderByNonceTx).item
-			if lowPriceTx == nil {
-				lowPriceTx = lastTx
-			}
-			if lowPriceTx.GasPrice.Cmp(lastTx.GasPrice.Value()) > 0 {
-				lowPriceTx = lastTx
+	return dropped, true, nil
+}
+
+func (s *pendingContainer) asSlice(limit int) []*types.Transaction {
+	slice := make([]*types.Transaction, 0)
+	for _, txSkip := range s.waitingMap {
+		for iter1 := txSkip.IterAtPosition(0); iter1.Next(); {
+			if len(slice) >= limit {
+				return slice
 			}
-		}
-		if lowPriceTx != nil {
-			s.remove(lowPriceTx)
+			slice = append(slice, iter1.Value().(*orderByNonceTx).item)
 		}
 	}
+	return slice
+}
 
-	return true
+func (s *pendingContainer) contains(tx *types.Transaction) bool {
+	list := s.waitingMap[*tx.Source]
+	if list == nil {
+		return false
+	}
+	exist := list.Get(newOrderByNonceTx(tx))[0]
+	return exist != nil && exist.(*orderByNonceTx).item.Hash == tx.Hash
 }
 
-func (s *pendingContainer) peek(f func(tx *types.Transaction) bool) {
-	if s.size == 0 {
+// remove deletes the transaction from the pending list. The one with the same nonce but a different hash is kept
+func (s *pendingContainer) remove(tx *types.Transaction) {
+	if !s.contains(tx) {
 		return
 	}
-	packingList := new(priceHeap)
-	heap.Init(packingList)
-
-	noncePositionMap := make(map[common.Address]uint64)
-	for _, list := range s.waitingMap {
-		heap.Push(packingList, list.ByPosition(0).(*orderByNonceTx).item)
+	list := s.waitingMap[*tx.Source]
+	list.Delete(newOrderByNonceTx(tx))
+	s.size--
+	if list.Len() == 0 {
+		delete(s.waitingMap, *tx.Source)
 	}
-	for {
-		if packingList.Len() == 0 {
-			return
-		}
-		tx := heap.Pop(packingList).(*types.Transaction)
-		if !f(tx) {
-			return
+}
+
+// queueContainer holds the future transactions which can't be put into pending list for now because of nonce gap.
+// Transactions are grouped by source and ordered by nonce, so they can be promoted in sequence
+type queueContainer struct {
+	accountLimit int // Max number of transactions for each source
+	size         int
+	locals       *txLocals // Local transactions are exempted from the account limit
+
+	waitingMap map[common.Address]*skip.SkipList //*orderByNonceTx. Map of future transactions group by source
+}
+
+// push the transaction into the queue. It returns the transactions dropped from the queue to make room for the given one,
+// and error if the given transaction is rejected.
+// The queue may exceed the limit after push, it is up to the container to evict the lowest price ones
+func (q *queueContainer) push(tx *types.Transaction) (dropped []*types.Transaction, err error) {
+	newTxNode := newOrderByNonceTx(tx)
+	list := q.waitingMap[*tx.Source]
+
+	// Replace the transaction with the same nonce if the new one pays more
+	if list != nil {
+		if exist := list.Get(newTxNode)[0]; exist != nil {
+			existTx := exist.(*orderByNonceTx).item
+			if err := checkPriceBump(existTx, tx); err != nil {
+				return nil, err
+			}
+			list.Insert(newTxNode)
+			return []*types.Transaction{existTx}, nil
 		}
-		next := noncePositionMap[*tx.Source] + 1
-		if s.waitingMap[*tx.Source] != nil && s.waitingMap[*tx.Source].Len() > next {
-			nextTx := s.waitingMap[*tx.Source].ByPosition(next).(*orderByNonceTx)
-			noncePositionMap[*tx.Source] = next
-			heap.Push(packingList, nextTx.item)
+	}
+
+	// The source uses up its slots, only lower nonce can take place of the highest one
+	if !q.locals.contains(tx) && list != nil && list.Len() >= uint64(q.accountLimit) {
+		lastTx := skipGetLast(list).(*orderByNonceTx).item
+		if tx.Nonce > lastTx.Nonce {
+			return nil, errAccountQueueFull
 		}
+		q.remove(lastTx)
+		dropped = append(dropped, lastTx)
+	}
+
+	if q.waitingMap[*tx.Source] == nil {
+		q.waitingMap[*tx.Source] = skip.New(uint16(16))
 	}
+	q.waitingMap[*tx.Source].Insert(newTxNode)
+	q.size++
+	return dropped, nil
 }
 
-func (s *pendingContainer) asSlice(limit int) []*types.Transaction {
-	slice := make([]*types.Transaction, 0, s.size)
-	count := 0
-	for _, txSkip := range s.waitingMap {
-		for iter1 := txSkip.IterAtPosition(0); iter1.Next(); {
-			slice = append(slice, iter1.Value().(*orderByNonceTx).item)
-			count++
-			if count >= limit {
-				break
-			}
-		}
+// first returns the lowest nonce transaction of the source
+func (q *queueContainer) first(source common.Address) *types.Transaction {
+	list := q.waitingMap[source]
+	if list == nil || list.Len() == 0 {
+		return nil
 	}
-	return slice
+	return list.ByPosition(0).(*orderByNonceTx).item
 }
 
-// will break when f(tx) returns false
-func (s *pendingContainer) eachForSync(f func(tx *types.Transaction) bool) {
-	for _, txSkip := range s.waitingMap {
-		for iter1 := txSkip.IterAtPosition(0); iter1.Next(); {
-			if !f(iter1.Value().(*orderByNonceTx).item) {
-				return
-			}
-		}
+func (q *queueContainer) contains(tx *types.Transaction) bool {
+	list := q.waitingMap[*tx.Source]
+	if list == nil {
+		return false
 	}
+	exist := list.Get(newOrderByNonceTx(tx))[0]
+	return exist != nil && exist.(*orderByNonceTx).item.Hash == tx.Hash
 }
 
-func (s *pendingContainer) remove(tx *types.Transaction) {
-	if s.waitingMap[*tx.Source] != nil {
-		deleted := s.waitingMap[*tx.Source].Delete(newOrderByNonceTx(tx))
-		s.size = s.size - len(deleted)
-		if s.waitingMap[*tx.Source].Len() == 0 {
-			delete(s.waitingMap, *tx.Source)
-		}
+func (q *queueContainer) remove(tx *types.Transaction) {
+	if !q.contains(tx) {
+		return
+	}
+	list := q.waitingMap[*tx.Source]
+	deleted := list.Delete(newOrderByNonceTx(tx))
+	q.size = q.size - len(deleted)
+	if list.Len() == 0 {
+		delete(q.waitingMap, *tx.Source)
+	}
+}
+
+func newQueueContainer(accountLimit int, locals *txLocals) *queueContainer {
+	return &queueContainer{
+		accountLimit: accountLimit,
+		size:         0,
+		locals:       locals,
+		waitingMap:   make(map[common.Address]*skip.SkipList),
 	}
 }
 
@@ -211,138 +376,658 @@ func newOrderByNonceTx(tx *types.Transaction) *orderByNonceTx {
 	return s
 }
 
-func newPendingContainer(limit int) *pendingContainer {
+func newPendingContainer() *pendingContainer {
 	s := &pendingContainer{
-		limit:      limit,
 		size:       0,
 		waitingMap: make(map[common.Address]*skip.SkipList),
 	}
 	return s
 }
 
-func newSimpleContainer(pendingLimit int, queueLimit int, nonceR nonceReader) *simpleContainer {
+// newSimpleContainer creates the container on the chain. The locally submitted transactions are journaled
+// into the given data source and replayed from it, or not journaled if it's nil
+func newSimpleContainer(pendingLimit int, queueLimit int, chain poolChain, journalDS *xchaindb.XchainDataSource) *simpleContainer {
+	locals := newTxLocals(TxLocalLimit)
 	c := &simpleContainer{
-		lock:       sync.RWMutex{},
-		nonceR:     nonceR,
-		txsMap:     make(map[common.Hash]*types.Transaction),
-		pending:    newPendingContainer(pendingLimit),
-		queue:      make(map[common.Hash]*types.Transaction),
-		queueLimit: queueLimit,
+		lock:         sync.RWMutex{},
+		pendingLimit: pendingLimit,
+		queueLimit:   queueLimit,
+		chain:        chain,
+		height:       chain.Height(),
+		lookup:       newTxLookup(),
+		locals:       locals,
+	}
+	accountLimit := queueLimitPerAccount
+	if accountLimit > queueLimit {
+		accountLimit = queueLimit
+	}
+	for i := range c.shards {
+		c.shards[i] = &txShard{
+			pending: newPendingContainer(),
+			queue:   newQueueContainer(accountLimit, locals),
+		}
+	}
+	if ctx := global.Context(); ctx != nil && ctx.Ticker != nil && ctx.Bus != nil {
+		c.startExpireRoutine(ctx.Ticker)
+		c.startBlockRoutine(ctx.Bus)
+		c.startRebroadcastRoutine(ctx.Ticker, ctx.Bus)
+		c.startAnnounceRoutine(ctx.Bus)
+	}
+	if journalDS != nil {
+		if err := initTxJournal(journalDS, c); err != nil {
+			logger.Errorf("init tx journal error:%v", err)
+		}
 	}
 	return c
 }
 
+func (c *simpleContainer) shardOf(source common.Address) *txShard {
+	return c.shards[source[common.AddressLength-1]%txShardCount]
+}
+
+// withShard runs f with the shard locked, and keeps the total sizes in step with the shard
+func (c *simpleContainer) withShard(s *txShard, f func(s *txShard)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pending, queued := s.pending.size, s.queue.size
+	f(s)
+	atomic.AddInt64(&c.pendingSize, int64(s.pending.size-pending))
+	atomic.AddInt64(&c.queueSize, int64(s.queue.size-queued))
+}
+
+// sizes returns the total number of transactions in pending lists and queues
+func (c *simpleContainer) sizes() (pending int, queued int) {
+	return int(atomic.LoadInt64(&c.pendingSize)), int(atomic.LoadInt64(&c.queueSize))
+}
+
 func (c *simpleContainer) Len() int {
-	return c.pending.size + len(c.queue)
+	pending, queued := c.sizes()
+	return pending + queued
 }
 
 func (c *simpleContainer) contains(key common.Hash) bool {
+	return c.lookup.contains(key)
+}
+
+func (c *simpleContainer) get(key common.Hash) *types.Transaction {
+	return c.lookup.get(key)
+}
+
+func (c *simpleContainer) asSlice(limit int) []*types.Transaction {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
-	return c.txsMap[key] != nil
+	txs := make([]*types.Transaction, 0)
+	for _, s := range c.shards {
+		if len(txs) >= limit {
+			break
+		}
+		s.lock.Lock()
+		txs = append(txs, s.pending.asSlice(limit-len(txs))...)
+		s.lock.Unlock()
+	}
+	return txs
 }
 
-func (c *simpleContainer) get(key common.Hash) *types.Transaction {
+// snapshot copies the transactions of each source in nonce order from the lists selected from each shard
+func (c *simpleContainer) snapshot(selector func(s *txShard) map[common.Address]*skip.SkipList) map[common.Address][]*types.Transaction {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
-	return c.txsMap[key]
+	snapshot := make(map[common.Address][]*types.Transaction)
+	for _, s := range c.shards {
+		s.lock.Lock()
+		for source, list := range selector(s) {
+			snapshot[source] = skipToSlice(list)
+		}
+		s.lock.Unlock()
+	}
+	return snapshot
 }
 
-// asSlice only working for rpc now, does not need the lock
-func (c *simpleContainer) asSlice(limit int) []*types.Transaction {
-	//c.lock.RLock()
-	//defer c.lock.RUnlock()
+func (c *simpleContainer) pendingSnapshot() map[common.Address][]*types.Transaction {
+	return c.snapshot(func(s *txShard) map[common.Address]*skip.SkipList {
+		return s.pending.waitingMap
+	})
+}
 
-	size := limit
-	if c.pending.size < size {
-		size = c.pending.size
-	}
-	txs := c.pending.asSlice(size)
-	return txs
+func (c *simpleContainer) queueSnapshot() map[common.Address][]*types.Transaction {
+	return c.snapshot(func(s *txShard) map[common.Address]*skip.SkipList {
+		return s.queue.waitingMap
+	})
 }
 
+// eachForPack calls f with the pending transactions in the order of gas price, and the transactions of the same source
+// in the order of nonce. It works on a snapshot of the pending lists, so packing won't block the pushing
 func (c *simpleContainer) eachForPack(f func(tx *types.Transaction) bool) {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
-	c.pending.peek(f)
+	c.eachForPackBy(gasPriceLess, func(tx *types.Transaction) (packed bool, next bool) {
+		return true, f(tx)
+	})
+}
+
+// eachForPackBy calls f with the pending transactions in the order of less, and the transactions of the same source
+// in the order of nonce. If f doesn't pack a transaction, the following ones of the same source are skipped
+// since they can't be executed without it
+func (c *simpleContainer) eachForPackBy(less func(a, b *types.Transaction) bool, f func(tx *types.Transaction) (packed bool, next bool)) {
+	lists := c.pendingSnapshot()
+	if len(lists) == 0 {
+		return
+	}
+	packingList := &packingHeap{txs: make([]*types.Transaction, 0, len(lists)), less: less}
+	for _, list := range lists {
+		packingList.txs = append(packingList.txs, list[0])
+	}
+	heap.Init(packingList)
+
+	noncePositionMap := make(map[common.Address]int)
+	for packingList.Len() > 0 {
+		tx := heap.Pop(packingList).(*types.Transaction)
+		packed, next := f(tx)
+		if !next {
+			return
+		}
+		if !packed {
+			continue
+		}
+		position := noncePositionMap[*tx.Source] + 1
+		if list := lists[*tx.Source]; len(list) > position {
+			noncePositionMap[*tx.Source] = position
+			heap.Push(packingList, list[position])
+		}
+	}
+}
+
+// PackingThreshold returns the lowest gas price packed into the next block
+// if the pending transactions are more than a block can hold, otherwise returns 0
+func (c *simpleContainer) PackingThreshold(gasLimit uint64) uint64 {
+	var (
+		used  uint64
+		price uint64
+		full  bool
+	)
+	c.eachForPack(func(tx *types.Transaction) bool {
+		used += tx.GasLimit.Uint64()
+		if used > gasLimit {
+			full = true
+			return false
+		}
+		price = tx.GasPrice.Uint64()
+		return true
+	})
+	if !full {
+		return 0
+	}
+	return price
 }
 
 func (c *simpleContainer) eachForSync(f func(tx *types.Transaction) bool) {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
-	c.pending.eachForSync(f)
+	for _, list := range c.pendingSnapshot() {
+		for _, tx := range list {
+			if !f(tx) {
+				return
+			}
+		}
+	}
 }
 
 // push try to push transaction to pool. if error return means the transaction is discarded and the error can be ignored
 func (c *simpleContainer) push(tx *types.Transaction) (err error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
+	return c.add(tx, false)
+}
 
-	if c.txsMap[tx.Hash] != nil {
+// add pushes the transaction into the pending list or queue. Local transaction takes one of the local slots,
+// and it is given back if the transaction is rejected.
+// Only the shard of the source is locked, unless the pool is full and some transactions have to be evicted
+func (c *simpleContainer) add(tx *types.Transaction, local bool) (err error) {
+	if local {
+		if err = c.locals.add(tx); err != nil {
+			return
+		}
+	}
+	if c.lookup.contains(tx.Hash) {
 		return
 	}
+	defer func() {
+		if err != nil {
+			c.locals.remove(tx)
+		}
+	}()
+
+	if err = checkExecutable(tx); err != nil {
+		return
+	}
+	if err = validateTxChainID(tx, atomic.LoadUint64(&c.height)+1); err != nil {
+		return
+	}
+	if err = validateTxWindow(tx, atomic.LoadUint64(&c.height)); err != nil {
+		return
+	}
+
 	stateNonce := c.getStateNonce(tx)
 	if tx.Nonce <= stateNonce || tx.Nonce > stateNonce+1000 {
 		err = logger.Warnf("Tx nonce error! expect nonce:%d,real nonce:%d ", stateNonce+1, tx.Nonce)
 		return
 	}
 
-	success := c.pending.push(tx, stateNonce)
-	if !success {
-		if len(c.queue) > c.queueLimit {
+	added := false
+	c.lock.RLock()
+	c.withShard(c.shardOf(*tx.Source), func(s *txShard) {
+		// Check again with the shard locked, the same transaction may arrive concurrently
+		if c.lookup.contains(tx.Hash) {
+			return
+		}
+		dropped, success, pushErr := s.pending.push(tx, stateNonce)
+		if pushErr == nil && !success {
+			var queueDropped []*types.Transaction
+			queueDropped, pushErr = s.queue.push(tx)
+			dropped = append(dropped, queueDropped...)
+		}
+		c.clearDropped(tx, dropped)
+		if pushErr != nil {
+			err = pushErr
 			return
 		}
-		c.queue[tx.Hash] = tx
+		c.lookup.add(tx, time.Now())
+		added = true
+	})
+	c.lock.RUnlock()
+	if err != nil || !added {
+		return err
 	}
-	c.txsMap[tx.Hash] = tx
-	return
+	if err = c.evictOverflow(tx); err != nil {
+		return err
+	}
+	if ctx := global.Context(); ctx != nil && ctx.Bus != nil {
+		ctx.Bus.Publish(notify.TxAdded, &types.TxAddedMessage{Tx: tx})
+	}
+	return nil
 }
 
-func (c *simpleContainer) remove(key common.Hash) {
-	if !c.contains(key) {
-		return
+// pushLocal pushes the transaction submitted from the local node, and records it into the journal if accepted,
+// so that it won't be lost when the node restarts
+func (c *simpleContainer) pushLocal(tx *types.Transaction) error {
+	if err := c.add(tx, true); err != nil {
+		return err
+	}
+	if c.journal != nil {
+		if err := c.journal.insert(tx); err != nil {
+			logger.Warnf("journal tx %v error:%v", tx.Hash.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// clearDropped removes the transactions dropped from pending list or queue for the given tx.
+// The one with the same source and nonce is replaced by tx, and a TxReplaced event will be published
+func (c *simpleContainer) clearDropped(tx *types.Transaction, dropped []*types.Transaction) {
+	for _, droppedTx := range dropped {
+		c.forget(droppedTx)
+		if droppedTx.Nonce == tx.Nonce && *droppedTx.Source == *tx.Source {
+			logger.Debugf("Tx %v replaced by %v, gas price %v -> %v", droppedTx.Hash.Hex(), tx.Hash.Hex(), droppedTx.GasPrice.Value(), tx.GasPrice.Value())
+			if ctx := global.Context(); ctx != nil && ctx.Bus != nil {
+				ctx.Bus.Publish(notify.TxReplaced, &types.TxReplacedMessage{Old: droppedTx, New: tx})
+			}
+		}
+	}
+}
+
+// evictOverflow evicts the lowest price transactions if the pending list or the queue exceeds the limit.
+// It returns error if tx itself is evicted, and tx is preferred to evict among those with the same price
+func (c *simpleContainer) evictOverflow(tx *types.Transaction) (err error) {
+	if pending, queued := c.sizes(); pending < c.pendingLimit && queued <= c.queueLimit {
+		return nil
 	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	tx := c.txsMap[key]
+
+	for pending, _ := c.sizes(); pending >= c.pendingLimit; pending, _ = c.sizes() {
+		lowPriceTx := c.lowestPriceTail(c.pendingLists, tx)
+		if lowPriceTx == nil {
+			break
+		}
+		c.withShard(c.shardOf(*lowPriceTx.Source), func(s *txShard) {
+			s.pending.remove(lowPriceTx)
+		})
+		c.forget(lowPriceTx)
+		if lowPriceTx == tx {
+			err = errPendingFull
+		}
+	}
+	for _, queued := c.sizes(); queued > c.queueLimit; _, queued = c.sizes() {
+		lowPriceTx := c.lowestPriceTail(c.queueLists, tx)
+		if lowPriceTx == nil {
+			break
+		}
+		c.withShard(c.shardOf(*lowPriceTx.Source), func(s *txShard) {
+			s.queue.remove(lowPriceTx)
+		})
+		c.forget(lowPriceTx)
+		if lowPriceTx == tx {
+			err = errQueueFull
+		}
+	}
+	return
+}
+
+func (c *simpleContainer) pendingLists(s *txShard) map[common.Address]*skip.SkipList {
+	return s.pending.waitingMap
+}
+
+func (c *simpleContainer) queueLists(s *txShard) map[common.Address]*skip.SkipList {
+	return s.queue.waitingMap
+}
+
+// lowestPriceTail returns the transaction with lowest gas price among the highest nonce transaction of each source
+// in the selected lists. Evicting the tail of the source won't make any nonce gap. Local transactions are skipped.
+// The caller must hold the container lock
+func (c *simpleContainer) lowestPriceTail(selector func(s *txShard) map[common.Address]*skip.SkipList, prefer *types.Transaction) *types.Transaction {
+	var lowPriceTx *types.Transaction
+	for _, s := range c.shards {
+		s.lock.Lock()
+		for _, list := range selector(s) {
+			lastTx := skipGetLast(list).(*orderByNonceTx).item
+			if c.locals.contains(lastTx) {
+				continue
+			}
+			if lowPriceTx == nil {
+				lowPriceTx = lastTx
+				continue
+			}
+			cmp := lowPriceTx.GasPrice.Cmp(lastTx.GasPrice.Value())
+			if cmp > 0 || (cmp == 0 && lastTx == prefer) {
+				lowPriceTx = lastTx
+			}
+		}
+		s.lock.Unlock()
+	}
+	return lowPriceTx
+}
+
+// remove deletes the transaction from the pool. The existence is checked again with the shard locked,
+// so it is safe to remove concurrently with the pushing and replacing of the same source
+func (c *simpleContainer) remove(key common.Hash) {
+	tx := c.lookup.get(key)
 	if tx == nil {
 		return
 	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	c.withShard(c.shardOf(*tx.Source), func(s *txShard) {
+		if !c.lookup.contains(key) {
+			return
+		}
+		c.forget(tx)
+		s.pending.remove(tx)
+		s.queue.remove(tx)
+	})
+}
 
-	delete(c.txsMap, key)
-	c.pending.remove(tx)
-	delete(c.queue, tx.Hash)
+// forget removes the index of the transaction
+func (c *simpleContainer) forget(tx *types.Transaction) {
+	c.lookup.remove(tx.Hash)
+	c.locals.remove(tx)
 }
 
-// promoteQueueToPending tris to move the transactions to the pending list for casting and syncing if possible
+// promoteQueueToPending tris to move the transactions to the pending list for casting and syncing if possible.
+// Transactions of each source are promoted in nonce order and it stops at the first nonce gap
 func (c *simpleContainer) promoteQueueToPending() {
+	c.lock.RLock()
+	for _, s := range c.shards {
+		c.withShard(s, c.promoteShard)
+	}
+	c.lock.RUnlock()
+
+	c.evictOverflow(nil)
+}
+
+func (c *simpleContainer) promoteShard(s *txShard) {
+	for source := range s.queue.waitingMap {
+		stateNonce := c.chain.GetNonce(source)
+		for tx := s.queue.first(source); tx != nil; tx = s.queue.first(source) {
+			// Stale transaction which nonce was already used on chain
+			if tx.Nonce <= stateNonce {
+				s.queue.remove(tx)
+				c.forget(tx)
+				continue
+			}
+			dropped, success, err := s.pending.push(tx, stateNonce)
+			c.clearDropped(tx, dropped)
+			if err != nil {
+				s.queue.remove(tx)
+				c.forget(tx)
+				break
+			}
+			if !success {
+				break
+			}
+			s.queue.remove(tx)
+		}
+	}
+}
+
+// evictExpired removes the transactions stay in the pool longer than TxLifetime since first seen.
+// Pending transactions of the same source with higher nonce are moved back to the queue,
+// because they can't be packed without the expired one. Local transactions never expire
+func (c *simpleContainer) evictExpired(now time.Time) (pending, queued []*types.Transaction) {
+	return c.evict(c.lookup.expired(now, TxLifetime), false, &c.expiredPending, &c.expiredQueued)
+}
+
+// evict removes the given transactions from the pool and adds them to the given counters.
+// Local transactions are kept unless includeLocals is set
+func (c *simpleContainer) evict(txs []*types.Transaction, includeLocals bool, pendingCount, queuedCount *uint64) (pending, queued []*types.Transaction) {
 	c.lock.Lock()
-	defer c.lock.Unlock()
-	nonceCache := make(map[common.Address]uint64)
-	for hash, tx := range c.queue {
-		//TODO: queue should order by nonce
-		stateNonce := c.getNonceWithCache(nonceCache, tx)
-		success := c.pending.push(tx, stateNonce)
-		if success {
-			delete(c.queue, hash)
+	for _, tx := range txs {
+		if !includeLocals && c.locals.contains(tx) {
+			continue
 		}
+		c.withShard(c.shardOf(*tx.Source), func(s *txShard) {
+			if s.queue.contains(tx) {
+				s.queue.remove(tx)
+				queued = append(queued, tx)
+			} else if s.pending.contains(tx) {
+				c.demoteAfter(s, tx)
+				s.pending.remove(tx)
+				pending = append(pending, tx)
+			}
+		})
+		c.forget(tx)
+	}
+	c.lock.Unlock()
+
+	atomic.AddUint64(pendingCount, uint64(len(pending)))
+	atomic.AddUint64(queuedCount, uint64(len(queued)))
+	// Demoted transactions may make the queue exceed the limit
+	c.evictOverflow(nil)
+	return
+}
+
+// demoteAfter moves the pending transactions of the same source whose nonce is higher than the given one to the queue
+func (c *simpleContainer) demoteAfter(s *txShard, tx *types.Transaction) {
+	list := s.pending.waitingMap[*tx.Source]
+	if list == nil {
+		return
+	}
+	for last := skipGetLast(list); last != nil; last = skipGetLast(list) {
+		higherTx := last.(*orderByNonceTx).item
+		if higherTx.Nonce <= tx.Nonce {
+			return
+		}
+		s.pending.remove(higherTx)
+		dropped, err := s.queue.push(higherTx)
+		for _, droppedTx := range dropped {
+			c.forget(droppedTx)
+		}
+		if err != nil {
+			c.forget(higherTx)
+		}
+	}
+}
+
+// expiredCount returns the total number of the expired transactions in pending list and queue
+func (c *simpleContainer) expiredCount() (pending, queued uint64) {
+	return atomic.LoadUint64(&c.expiredPending), atomic.LoadUint64(&c.expiredQueued)
+}
+
+// startExpireRoutine registers the periodic sweep of the expired transactions on the given ticker
+func (c *simpleContainer) startExpireRoutine(gt *ticker.GlobalTicker) {
+	gt.RegisterPeriodicRoutine(txExpireRoutine, func() bool {
+		pending, queued := c.evictExpired(time.Now())
+		if len(pending) == 0 && len(queued) == 0 {
+			return true
+		}
+		totalPending, totalQueued := c.expiredCount()
+		logger.Infof("Tx expired, pending %v, queued %v, total expired pending %v, queued %v", len(pending), len(queued), totalPending, totalQueued)
+		global.Context().Bus.Publish(notify.TxExpired, &types.TxExpiredMessage{Pending: pending, Queued: queued})
+		return true
+	}, txExpireInterval)
+	gt.StartTickerRoutine(txExpireRoutine, false)
+}
+
+// startBlockRoutine follows the blocks added on chain, so that the transactions are checked against the latest height
+func (c *simpleContainer) startBlockRoutine(bus *notify.Bus) {
+	bus.Subscribe(notify.BlockAddSucc, func(message notify.Message) {
+		block, ok := message.GetData().(*types.Block)
+		if !ok || block == nil || block.Header == nil {
+			return
+		}
+		pending, queued := c.onBlockAdded(block.Header.Height)
+		if len(pending) == 0 && len(queued) == 0 {
+			return
+		}
+		logger.Infof("Tx validity window expired at height %v, pending %v, queued %v", block.Header.Height, len(pending), len(queued))
+		bus.Publish(notify.TxExpired, &types.TxExpiredMessage{Pending: pending, Queued: queued})
+	})
+}
+
+// onBlockAdded updates the height of the latest block on chain seen by the pool,
+// and prunes the transactions out of their validity windows at the height
+func (c *simpleContainer) onBlockAdded(height uint64) (pending, queued []*types.Transaction) {
+	atomic.StoreUint64(&c.height, height)
+	return c.evictOutOfWindow(height)
+}
+
+// TxPoolStatus is the statistics of the transactions in the pool
+type TxPoolStatus struct {
+	Pending        int    `json:"pending"`
+	Queued         int    `json:"queued"`
+	ExpiredPending uint64 `json:"expired_pending"`
+	ExpiredQueued  uint64 `json:"expired_queued"`
+
+	OutOfWindowPending uint64 `json:"out_of_window_pending"` // Pruned since the validity window ended
+	OutOfWindowQueued  uint64 `json:"out_of_window_queued"`
+}
+
+// Reasons of the transaction being queued rather than pending
+const (
+	TxQueuedNonceGap     = "nonce gap"          // Some lower nonce transactions of the source are missing
+	TxQueuedPromoting    = "promoting"          // The nonce is continuous, it will be moved to pending on next promotion
+	TxQueuedLowestPrice  = "lowest gas price"   // The queue is full and it will be the first to evict
+	TxQueuedAccountLimit = "account slots full" // The source uses up its queue slots, higher nonce ones will be rejected
+)
+
+// TxInspection describes where the transaction is in the pool and why
+type TxInspection struct {
+	Status        string   `json:"status"`         // pending, queued or unknown
+	Local         bool     `json:"local"`          // Submitted through the rpc of this node
+	Reasons       []string `json:"reasons"`        // Reasons of the transaction being queued
+	StateNonce    uint64   `json:"state_nonce"`    // Nonce of the source on chain
+	ExpectedNonce uint64   `json:"expected_nonce"` // The next nonce the source should send to fill the gap
+}
+
+// Status returns the statistics of the pool
+func (c *simpleContainer) Status() *TxPoolStatus {
+	pending, queued := c.sizes()
+	expiredPending, expiredQueued := c.expiredCount()
+	return &TxPoolStatus{
+		Pending:        pending,
+		Queued:         queued,
+		ExpiredPending: expiredPending,
+		ExpiredQueued:  expiredQueued,
+
+		OutOfWindowPending: atomic.LoadUint64(&c.outOfWindowPending),
+		OutOfWindowQueued:  atomic.LoadUint64(&c.outOfWindowQueued),
 	}
 }
 
-func (c *simpleContainer) getNonceWithCache(cache map[common.Address]uint64, tx *types.Transaction) uint64 {
-	if cache[*tx.Source] != 0 {
-		return cache[*tx.Source]
+// Content returns all the transactions in the pool grouped by source and ordered by nonce
+func (c *simpleContainer) Content() (pending, queued map[common.Address][]*types.Transaction) {
+	return c.pendingSnapshot(), c.queueSnapshot()
+}
+
+// ContentFrom returns the transactions of the given source in the pool ordered by nonce
+func (c *simpleContainer) ContentFrom(source common.Address) (pending, queued []*types.Transaction) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	s := c.shardOf(source)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if list := s.pending.waitingMap[source]; list != nil {
+		pending = skipToSlice(list)
 	}
-	nonce := c.nonceR.GetNonce(*tx.Source)
-	cache[*tx.Source] = nonce
-	return nonce
+	if list := s.queue.waitingMap[source]; list != nil {
+		queued = skipToSlice(list)
+	}
+	return
+}
+
+// Inspect finds out where the transaction is in the pool, and the reasons if it is queued
+func (c *simpleContainer) Inspect(hash common.Hash) *TxInspection {
+	tx := c.lookup.get(hash)
+	if tx == nil {
+		return &TxInspection{Status: "unknown"}
+	}
+	stateNonce := c.getStateNonce(tx)
+	local := c.locals.contains(tx)
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var (
+		expected    = stateNonce + 1
+		isQueued    bool
+		accountFull bool
+	)
+	c.withShard(c.shardOf(*tx.Source), func(s *txShard) {
+		if list := s.pending.waitingMap[*tx.Source]; list != nil && list.Len() > 0 {
+			expected = skipGetLast(list).(*orderByNonceTx).item.Nonce + 1
+		}
+		isQueued = s.queue.contains(tx)
+		accountFull = isQueued && s.queue.waitingMap[*tx.Source].Len() >= uint64(s.queue.accountLimit)
+	})
+	if !isQueued {
+		return &TxInspection{Status: "pending", Local: local, StateNonce: stateNonce, ExpectedNonce: expected}
+	}
+
+	inspection := &TxInspection{Status: "queued", Local: local, StateNonce: stateNonce, ExpectedNonce: expected}
+	if tx.Nonce > expected {
+		inspection.Reasons = append(inspection.Reasons, TxQueuedNonceGap)
+	} else {
+		inspection.Reasons = append(inspection.Reasons, TxQueuedPromoting)
+	}
+	if _, queued := c.sizes(); queued >= c.queueLimit && c.lowestPriceTail(c.queueLists, nil) == tx {
+		inspection.Reasons = append(inspection.Reasons, TxQueuedLowestPrice)
+	}
+	if !local && accountFull {
+		inspection.Reasons = append(inspection.Reasons, TxQueuedAccountLimit)
+	}
+	return inspection
 }
 
 // getStateNonce fetches nonce from current state db
 func (c *simpleContainer) getStateNonce(tx *types.Transaction) uint64 {
-	return c.nonceR.GetNonce(*tx.Source)
+	return c.chain.GetNonce(*tx.Source)
+}
+
+func skipToSlice(skip *skip.SkipList) []*types.Transaction {
+	txs := make([]*types.Transaction, 0, skip.Len())
+	for iter := skip.IterAtPosition(0); iter.Next(); {
+		txs = append(txs, iter.Value().(*orderByNonceTx).item)
+	}
+	return txs
 }
 
 func skipGetLast(skip *skip.SkipList) datacommon.Comparator {
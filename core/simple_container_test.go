@@ -0,0 +1,610 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"math/big"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/crypto"
+	"github.com/xchain/go-chain/global/types"
+	"github.com/xchain/go-chain/middleware/notify"
+)
+
+// testChainID is the chain the tests run on, the mock transactions are signed for it
+const testChainID = 1
+
+func TestMain(m *testing.M) {
+	ChainID = testChainID
+	os.Exit(m.Run())
+}
+
+type mockNonceReader map[common.Address]uint64
+
+func (m mockNonceReader) GetNonce(address common.Address) uint64 {
+	return m[address]
+}
+
+func (m mockNonceReader) GetBalance(address common.Address) *big.Int {
+	return new(big.Int)
+}
+
+func (m mockNonceReader) LatestStateDB() types.AccountDB {
+	return nil
+}
+
+func (m mockNonceReader) Height() uint64 {
+	return 0
+}
+
+// mockHeightChain is the chain at the given height
+type mockHeightChain struct {
+	mockNonceReader
+	height uint64
+}
+
+func (m mockHeightChain) Height() uint64 {
+	return m.height
+}
+
+func newMockTx(source common.Address, nonce uint64, gasPrice uint64) *types.Transaction {
+	return newTypedTx(types.TransactionTypeTransfer, source, nonce, gasPrice, nil)
+}
+
+// newTypedTx returns a transaction of the type from the source to itself, with the other fields set by fill before hashing
+func newTypedTx(typ int8, source common.Address, nonce uint64, gasPrice uint64, fill func(tx *types.Transaction)) *types.Transaction {
+	tx := &types.Transaction{
+		Value:    types.NewBigInt(1),
+		Nonce:    nonce,
+		Type:     typ,
+		Target:   &source,
+		GasLimit: types.NewBigInt(3000),
+		GasPrice: types.NewBigInt(gasPrice),
+		Source:   &source,
+		ChainID:  testChainID,
+	}
+	if fill != nil {
+		fill(tx)
+	}
+	tx.Hash = tx.GenHash()
+	return tx
+}
+
+func TestSimpleContainer_PromoteInNonceOrder(t *testing.T) {
+	source := common.BytesToAddress([]byte("relayer"))
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+
+	// push future transactions in reversed order, all of them should be queued
+	for nonce := uint64(60); nonce >= 3; nonce-- {
+		if err := c.push(newMockTx(source, nonce, 1000)); err != nil {
+			t.Fatalf("push nonce %v error:%v", nonce, err)
+		}
+	}
+	if pending, queued := c.sizes(); queued != 58 || pending != 0 {
+		t.Fatalf("unexpected size, queue %v pending %v", queued, pending)
+	}
+
+	c.push(newMockTx(source, 1, 1000))
+	c.push(newMockTx(source, 2, 1000))
+	c.promoteQueueToPending()
+
+	if pending, queued := c.sizes(); queued != 0 || pending != 60 {
+		t.Fatalf("unexpected size after promote, queue %v pending %v", queued, pending)
+	}
+	expect := uint64(1)
+	c.eachForPack(func(tx *types.Transaction) bool {
+		if tx.Nonce != expect {
+			t.Fatalf("pack out of order, expect nonce %v, got %v", expect, tx.Nonce)
+		}
+		expect++
+		return true
+	})
+}
+
+func TestSimpleContainer_PromoteStopsAtGap(t *testing.T) {
+	source := common.BytesToAddress([]byte("1"))
+	nonces := mockNonceReader{}
+	c := newSimpleContainer(1000, 1000, nonces, nil)
+
+	for _, nonce := range []uint64{3, 4, 6, 7} {
+		c.push(newMockTx(source, nonce, 1000))
+	}
+	// nonce 1 and 2 were included by others, so 3 and 4 can be promoted, 6 and 7 wait for nonce 5
+	nonces[source] = 2
+	c.promoteQueueToPending()
+
+	if pending, queued := c.sizes(); pending != 2 || queued != 2 {
+		t.Fatalf("unexpected size after promote, queue %v pending %v", queued, pending)
+	}
+	if first := c.shardOf(source).queue.first(source); first == nil || first.Nonce != 6 {
+		t.Fatalf("unexpected first queued tx %v", first)
+	}
+}
+
+func TestSimpleContainer_QueueAccountLimit(t *testing.T) {
+	spammer := common.BytesToAddress([]byte("spammer"))
+	other := common.BytesToAddress([]byte("other"))
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+
+	for nonce := uint64(2); nonce < 2+queueLimitPerAccount; nonce++ {
+		if err := c.push(newMockTx(spammer, nonce, 1000)); err != nil {
+			t.Fatalf("push nonce %v error:%v", nonce, err)
+		}
+	}
+	if err := c.push(newMockTx(spammer, 2+queueLimitPerAccount, 1000)); err != errAccountQueueFull {
+		t.Fatalf("expect account queue full, got %v", err)
+	}
+	if err := c.push(newMockTx(other, 2, 1000)); err != nil {
+		t.Fatalf("other source should not be affected:%v", err)
+	}
+	if _, queued := c.sizes(); queued != queueLimitPerAccount+1 || c.lookup.len() != queueLimitPerAccount+1 {
+		t.Fatalf("unexpected queue size %v, txs %v", queued, c.lookup.len())
+	}
+}
+
+func TestSimpleContainer_QueueEvictLowestPrice(t *testing.T) {
+	c := newSimpleContainer(10, 3, mockNonceReader{}, nil)
+
+	cheap := newMockTx(common.BytesToAddress([]byte("1")), 5, 100)
+	c.push(cheap)
+	c.push(newMockTx(common.BytesToAddress([]byte("2")), 5, 300))
+	c.push(newMockTx(common.BytesToAddress([]byte("3")), 5, 200))
+
+	if err := c.push(newMockTx(common.BytesToAddress([]byte("4")), 5, 50)); err != errQueueFull {
+		t.Fatalf("expect queue full, got %v", err)
+	}
+	if err := c.push(newMockTx(common.BytesToAddress([]byte("4")), 5, 400)); err != nil {
+		t.Fatalf("push error:%v", err)
+	}
+	if _, queued := c.sizes(); queued != 3 {
+		t.Fatalf("unexpected queue size %v", queued)
+	}
+	if c.get(cheap.Hash) != nil {
+		t.Fatalf("lowest price tx should be evicted")
+	}
+}
+
+func TestSimpleContainer_ReplaceByFee(t *testing.T) {
+	source := common.BytesToAddress([]byte("1"))
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+
+	pendingTx := newMockTx(source, 1, 1000)
+	queuedTx := newMockTx(source, 5, 1000)
+	c.push(pendingTx)
+	c.push(queuedTx)
+
+	for _, old := range []*types.Transaction{pendingTx, queuedTx} {
+		err := c.push(newMockTx(source, old.Nonce, 1050))
+		if e, ok := err.(*ReplaceUnderpricedError); !ok || e.MinGasPrice.Uint64() != 1100 || e.Exist != old.Hash {
+			t.Fatalf("expect replace underpriced error, got %v", err)
+		}
+
+		replacement := newMockTx(source, old.Nonce, 1100)
+		if err := c.push(replacement); err != nil {
+			t.Fatalf("replace error:%v", err)
+		}
+		if c.get(old.Hash) != nil || c.get(replacement.Hash) == nil {
+			t.Fatalf("replaced tx should be removed from pool")
+		}
+	}
+	if pending, queued := c.sizes(); pending != 1 || queued != 1 || c.lookup.len() != 2 {
+		t.Fatalf("unexpected size, pending %v queue %v txs %v", pending, queued, c.lookup.len())
+	}
+}
+
+func TestSimpleContainer_EvictExpired(t *testing.T) {
+	source := common.BytesToAddress([]byte("1"))
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+
+	txs := make([]*types.Transaction, 0)
+	for _, nonce := range []uint64{1, 2, 3, 5} {
+		tx := newMockTx(source, nonce, 1000)
+		c.push(tx)
+		txs = append(txs, tx)
+	}
+	// nonce 2 stays too long, nonce 3 has to wait for it again
+	c.lookup.add(txs[1], time.Now().Add(-TxLifetime))
+	c.lookup.add(txs[3], time.Now().Add(-TxLifetime))
+
+	pending, queued := c.evictExpired(time.Now())
+	if len(pending) != 1 || pending[0] != txs[1] || len(queued) != 1 || queued[0] != txs[3] {
+		t.Fatalf("unexpected expired txs, pending %v queued %v", pending, queued)
+	}
+	if pending, queued := c.sizes(); pending != 1 || queued != 1 || c.Len() != c.lookup.len() {
+		t.Fatalf("unexpected size, pending %v queue %v txs %v", pending, queued, c.lookup.len())
+	}
+	if first := c.shardOf(source).queue.first(source); first != txs[2] {
+		t.Fatalf("higher nonce tx should be demoted to queue")
+	}
+	if totalPending, totalQueued := c.expiredCount(); totalPending != 1 || totalQueued != 1 {
+		t.Fatalf("unexpected expired count %v %v", totalPending, totalQueued)
+	}
+}
+
+func TestSimpleContainer_Inspect(t *testing.T) {
+	source := common.BytesToAddress([]byte("1"))
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+
+	pendingTx := newMockTx(source, 1, 1000)
+	gapTx := newMockTx(source, 3, 1000)
+	c.push(pendingTx)
+	c.push(gapTx)
+
+	if status := c.Status(); status.Pending != 1 || status.Queued != 1 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if inspection := c.Inspect(pendingTx.Hash); inspection.Status != "pending" {
+		t.Fatalf("unexpected inspection %+v", inspection)
+	}
+	inspection := c.Inspect(gapTx.Hash)
+	if inspection.Status != "queued" || inspection.ExpectedNonce != 2 || inspection.Reasons[0] != TxQueuedNonceGap {
+		t.Fatalf("unexpected inspection %+v", inspection)
+	}
+	if inspection := c.Inspect(common.Hash{}); inspection.Status != "unknown" {
+		t.Fatalf("unexpected inspection %+v", inspection)
+	}
+
+	pending, queued := c.ContentFrom(source)
+	if len(pending) != 1 || pending[0] != pendingTx || len(queued) != 1 || queued[0] != gapTx {
+		t.Fatalf("unexpected content, pending %v queued %v", pending, queued)
+	}
+}
+
+func TestSimpleContainer_LocalExemption(t *testing.T) {
+	local := common.BytesToAddress([]byte("local"))
+	c := newSimpleContainer(2, 2, mockNonceReader{}, nil)
+
+	// Local transactions are pushed beyond the limits of pending list and queue
+	for _, nonce := range []uint64{1, 2, 3, 5, 6, 7} {
+		if err := c.pushLocal(newMockTx(local, nonce, 1)); err != nil {
+			t.Fatalf("push local nonce %v error:%v", nonce, err)
+		}
+	}
+	if pending, queued := c.sizes(); pending != 3 || queued != 3 {
+		t.Fatalf("unexpected size, pending %v queue %v", pending, queued)
+	}
+
+	// Remote ones can't evict the local ones even if they pay more
+	remote := newMockTx(common.BytesToAddress([]byte("remote")), 5, 1000)
+	if err := c.push(remote); err != errQueueFull {
+		t.Fatalf("expect queue full, got %v", err)
+	}
+	if len(c.localTxs()) != 6 {
+		t.Fatalf("unexpected local txs %v", len(c.localTxs()))
+	}
+
+	// Local transactions never expire and leave the local set once removed
+	for _, tx := range c.localTxs() {
+		c.lookup.add(tx, time.Now().Add(-TxLifetime))
+	}
+	if pending, queued := c.evictExpired(time.Now()); len(pending) != 0 || len(queued) != 0 {
+		t.Fatalf("local txs should not expire")
+	}
+	for _, tx := range c.localTxs() {
+		c.remove(tx.Hash)
+	}
+	if len(c.locals.txs) != 0 || c.Len() != 0 {
+		t.Fatalf("unexpected locals %v, pool size %v", len(c.locals.txs), c.Len())
+	}
+}
+
+func TestSimpleContainer_LocalLimit(t *testing.T) {
+	defer func(limit int) { TxLocalLimit = limit }(TxLocalLimit)
+	TxLocalLimit = 2
+
+	source := common.BytesToAddress([]byte("local"))
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	c.pushLocal(newMockTx(source, 1, 1000))
+	c.pushLocal(newMockTx(source, 2, 1000))
+
+	if err := c.pushLocal(newMockTx(source, 3, 1000)); err != errLocalFull {
+		t.Fatalf("expect local full, got %v", err)
+	}
+	// Replacement doesn't take more slot
+	if err := c.pushLocal(newMockTx(source, 2, 2000)); err != nil {
+		t.Fatalf("replace local error:%v", err)
+	}
+	if len(c.locals.txs) != 2 || c.Len() != 2 {
+		t.Fatalf("unexpected locals %v, pool size %v", len(c.locals.txs), c.Len())
+	}
+}
+
+func TestSimpleContainer_Rebroadcast(t *testing.T) {
+	bus := notify.NewBus()
+	received := make(chan []*types.Transaction, 1)
+	bus.Subscribe(notify.TxBroadcast, func(message notify.Message) {
+		txs, err := types.UnMarshalTransactions(message.GetRaw())
+		if err != nil {
+			t.Errorf("unmarshal broadcast txs error:%v", err)
+		}
+		received <- txs
+	})
+
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	c.push(newMockTx(common.BytesToAddress([]byte("remote")), 1, 1000))
+	if n, err := c.rebroadcast(bus); n != 0 || err != nil {
+		t.Fatalf("remote txs should not be rebroadcast, got %v %v", n, err)
+	}
+	sk, _ := crypto.GenerateKey("")
+	local := newSignedTx(sk, 1, 1000)
+	c.pushLocal(local)
+	if n, err := c.rebroadcast(bus); n != 1 || err != nil {
+		t.Fatalf("unexpected rebroadcast %v %v", n, err)
+	}
+	select {
+	case txs := <-received:
+		if len(txs) != 1 || txs[0].Hash != local.Hash {
+			t.Fatalf("unexpected broadcast txs %v", txs)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("local txs not broadcast")
+	}
+}
+
+func TestSimpleContainer_AddAnnounced(t *testing.T) {
+	bus := notify.NewBus()
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	c.startAnnounceRoutine(bus)
+
+	sk, _ := crypto.GenerateKey("")
+	tx := newSignedTx(sk, 1, 1000)
+	// The sign of the transaction is moved onto another content with the same hash
+	forged := newSignedTx(sk, 2, 1000)
+	forged.Nonce = 3
+	raw, err := types.MarshalTransactions([]*types.Transaction{tx, forged})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(notify.TxAnnounce, types.NewDefaultMessage(raw, "peer", 0, 0))
+	for deadline := time.Now().Add(time.Second); c.Len() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("announced txs not added")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if c.Len() != 1 || c.get(tx.Hash) == nil {
+		t.Fatalf("only the valid announced tx should be added, got %v", c.Len())
+	}
+	// Announced ones are remote, so they are not rebroadcast by this node
+	if n, _ := c.rebroadcast(bus); n != 0 {
+		t.Fatalf("announced txs should not be rebroadcast")
+	}
+}
+
+// testConcurrentPushRemove pushes and removes the transactions of 8 sources concurrently while packing,
+// and checks the pool is consistent afterwards
+func testConcurrentPushRemove(t *testing.T, sourceOf func(i int) common.Address) {
+	c := newSimpleContainer(100000, 100000, mockNonceReader{}, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			source := sourceOf(i)
+			for nonce := uint64(1); nonce <= 200; nonce++ {
+				tx := newMockTx(source, nonce, 1000)
+				c.push(tx)
+				if nonce%2 == 0 {
+					c.remove(tx.Hash)
+					c.push(tx)
+				}
+			}
+		}(i)
+	}
+	// Packing and lookups go on while pushing
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				c.eachForPack(func(tx *types.Transaction) bool {
+					return c.contains(tx.Hash)
+				})
+			}
+		}
+	}()
+	wg.Wait()
+	close(stop)
+
+	if c.Len() != c.lookup.len() {
+		t.Fatalf("size mismatch, pool %v lookup %v", c.Len(), c.lookup.len())
+	}
+	count := 0
+	c.eachForPack(func(tx *types.Transaction) bool {
+		count++
+		return true
+	})
+	if pending, _ := c.sizes(); count != pending {
+		t.Fatalf("size mismatch, packed %v pending %v", count, pending)
+	}
+	for i := 0; i < 8; i++ {
+		if pending, queued := c.ContentFrom(sourceOf(i)); len(pending)+len(queued) != 200 {
+			t.Fatalf("source %v expect 200 txs, got %v", i, len(pending)+len(queued))
+		}
+	}
+}
+
+func TestSimpleContainer_ConcurrentPushRemove(t *testing.T) {
+	testConcurrentPushRemove(t, func(i int) common.Address {
+		return common.BytesToAddress([]byte{byte(i)})
+	})
+}
+
+// The sources sharing the last byte land in the same shard, so they contend on the same shard lock
+func TestSimpleContainer_ConcurrentPushRemoveSameShard(t *testing.T) {
+	c := newSimpleContainer(1, 1, mockNonceReader{}, nil)
+	sourceOf := func(i int) common.Address {
+		return common.BytesToAddress([]byte{byte(i + 1), 0x2a})
+	}
+	for i := 1; i < 8; i++ {
+		if c.shardOf(sourceOf(i)) != c.shardOf(sourceOf(0)) {
+			t.Fatalf("source %v not in the same shard", i)
+		}
+	}
+	testConcurrentPushRemove(t, sourceOf)
+}
+
+// newBenchSource returns a distinct source for each call, so the parallel pushing goroutines won't share nonces
+func newBenchSource(seq *uint64) common.Address {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, atomic.AddUint64(seq, 1))
+	return common.BytesToAddress(b)
+}
+
+func benchPush(b *testing.B, c *simpleContainer, seq *uint64) {
+	b.RunParallel(func(pb *testing.PB) {
+		source := newBenchSource(seq)
+		nonce := uint64(0)
+		for pb.Next() {
+			if nonce++; nonce > 1000 {
+				source, nonce = newBenchSource(seq), 1
+			}
+			c.push(newMockTx(source, nonce, 1000+nonce%7))
+		}
+	})
+}
+
+func BenchmarkSimpleContainer_Push(b *testing.B) {
+	var seq uint64
+	c := newSimpleContainer(1<<30, 1<<30, mockNonceReader{}, nil)
+	b.ResetTimer()
+	benchPush(b, c, &seq)
+}
+
+func BenchmarkSimpleContainer_PushWhilePacking(b *testing.B) {
+	var (
+		seq   uint64
+		packs uint64
+	)
+	c := newSimpleContainer(1<<30, 1<<30, mockNonceReader{}, nil)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				packed := 0
+				c.eachForPack(func(tx *types.Transaction) bool {
+					packed++
+					return packed < 1000
+				})
+				packs++
+			}
+		}
+	}()
+	start := time.Now()
+	b.ResetTimer()
+	benchPush(b, c, &seq)
+	b.StopTimer()
+	close(stop)
+	<-done
+	b.Logf("%v packs in %v while pushing %v txs", packs, time.Since(start), b.N)
+}
+
+func BenchmarkSimpleContainer_LookupWhilePushing(b *testing.B) {
+	var seq uint64
+	c := newSimpleContainer(1<<30, 1<<30, mockNonceReader{}, nil)
+	source := newBenchSource(&seq)
+	hashes := make([]common.Hash, 1000)
+	for i := range hashes {
+		tx := newMockTx(source, uint64(i+1), 1000)
+		c.push(tx)
+		hashes[i] = tx.Hash
+	}
+	stop := make(chan struct{})
+	go func() {
+		s, nonce := newBenchSource(&seq), uint64(0)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if nonce++; nonce > 1000 {
+					s, nonce = newBenchSource(&seq), 1
+				}
+				c.push(newMockTx(s, nonce, 1000))
+			}
+		}
+	}()
+	defer close(stop)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.contains(hashes[i%len(hashes)])
+			i++
+		}
+	})
+}
+
+func TestSimpleContainer_RejectNotExecutableType(t *testing.T) {
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	source := common.BytesToAddress([]byte("type"))
+	tx := newMockTx(source, 1, 1000)
+	tx.Type = types.TransactionTypeHTLCLock
+	tx.Hash = tx.GenHash()
+	if err := c.push(tx); err != errTxTypeNotSupport {
+		t.Fatalf("expect type rejected, got %v", err)
+	}
+	tx = newMockTx(source, 1, 1000)
+	tx.MultiSign = &types.MultiSign{Account: source}
+	tx.Hash = tx.GenHash()
+	if err := c.push(tx); err != errTxMultiSignNotSupport {
+		t.Fatalf("expect multi-signed rejected, got %v", err)
+	}
+	sender, _ := crypto.GenerateKey("")
+	payer, _ := crypto.GenerateKey("")
+	if err := c.push(newSponsoredTx(sender, payer, 1, 1000)); err != errTxSponsorNotSupport {
+		t.Fatalf("expect sponsored rejected, got %v", err)
+	}
+	if err := c.push(newMockTx(source, 1, 1000)); err != nil || c.Len() != 1 {
+		t.Fatalf("transfer should be accepted: %v", err)
+	}
+}
+
+func TestSimpleContainer_HeightFollowsBlocks(t *testing.T) {
+	source := common.BytesToAddress([]byte("height"))
+	bus := notify.NewBus()
+	c := newSimpleContainer(1000, 1000, mockHeightChain{mockNonceReader{}, 5}, nil)
+	c.startBlockRoutine(bus)
+
+	if err := c.push(newWindowTx(source, 1, 0, 6)); err != nil {
+		t.Fatalf("push error:%v", err)
+	}
+	bus.Publish(notify.BlockAddSucc, &types.BlockOnChainSuccMessage{Block: &types.Block{Header: &types.BlockHeader{Height: 6}}})
+	for deadline := time.Now().Add(time.Second); atomic.LoadUint64(&c.height) != 6; {
+		if time.Now().After(deadline) {
+			t.Fatalf("height not updated by the block event")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := c.push(newWindowTx(source, 2, 0, 6)); err != types.ErrTxWindowExpired {
+		t.Fatalf("tx expired at the new height should be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+var (
+	// KeyOfDelegatedAmount is the data key of the total stake delegated to the miner
+	KeyOfDelegatedAmount = []byte("delegated_stake")
+
+	// keyPrefixOfDelegation prefixes the data keys of the stake delegated by each delegator,
+	// stored in the account of the miner
+	keyPrefixOfDelegation = []byte("delegation_")
+)
+
+func keyOfDelegation(delegator common.Address) []byte {
+	return append(common.CopyBytes(keyPrefixOfDelegation), delegator.Bytes()...)
+}
+
+func getAmount(db types.AccountDB, addr common.Address, key []byte) *big.Int {
+	data := db.GetData(addr, key)
+	if len(data) == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).SetBytes(data)
+}
+
+func setAmount(db types.AccountDB, addr common.Address, key []byte, amount *big.Int) {
+	if amount.Sign() == 0 {
+		db.RemoveData(addr, key)
+		return
+	}
+	db.SetData(addr, key, amount.Bytes())
+}
+
+// Delegation is the stake delegated to a miner by a delegator
+type Delegation struct {
+	Delegator common.Address
+	Amount    *big.Int
+}
+
+// PledgeDetail is the breakdown of the stake of a miner
+type PledgeDetail struct {
+	Own         *big.Int // Staked by the miner itself
+	Delegated   *big.Int // Total stake delegated by the others
+	Delegations []*Delegation
+}
+
+// Total returns the stake counted for the miner
+func (p *PledgeDetail) Total() *big.Int {
+	return new(big.Int).Add(p.Own, p.Delegated)
+}
+
+// GetDelegation returns the stake delegated by the delegator to the miner
+func GetDelegation(db types.AccountDB, miner, delegator common.Address) *big.Int {
+	return getAmount(db, miner, keyOfDelegation(delegator))
+}
+
+// GetPledgeDetail returns the stake of the miner with each delegation listed.
+// Delegations are read from the committed state, so the ones changed in the current block are not listed
+func GetPledgeDetail(db types.AccountDB, miner common.Address) *PledgeDetail {
+	detail := &PledgeDetail{
+		Own:         getAmount(db, miner, KeyOfstakeAmount),
+		Delegated:   getAmount(db, miner, KeyOfDelegatedAmount),
+		Delegations: make([]*Delegation, 0),
+	}
+	iter := db.DataIterator(miner, keyPrefixOfDelegation)
+	if iter == nil {
+		return detail
+	}
+	for iter.Next() {
+		if !bytes.HasPrefix(iter.Key, keyPrefixOfDelegation) {
+			break
+		}
+		detail.Delegations = append(detail.Delegations, &Delegation{
+			Delegator: common.BytesToAddress(iter.Key[len(keyPrefixOfDelegation):]),
+			Amount:    new(big.Int).SetBytes(iter.Value),
+		})
+	}
+	return detail
+}
+
+// stakePledgeMgr counts the pledge of a miner as its own stake plus the stake delegated to it.
+// It replaces the pledge manager of the chain together with the executor dispatching the delegation types
+type stakePledgeMgr struct{}
+
+func (stakePledgeMgr) getPledge(db types.AccountDB, address common.Address) uint64 {
+	own := getAmount(db, address, KeyOfstakeAmount)
+	return own.Add(own, getAmount(db, address, KeyOfDelegatedAmount)).Uint64()
+}
+
+// validateStakeDelegation checks the delegating and undelegating transaction before it enters the pool
+func validateStakeDelegation(tx *types.Transaction) error {
+	if tx.Target == nil {
+		return fmt.Errorf("delegation should have the miner as target")
+	}
+	if tx.Value == nil || tx.Value.Sign() <= 0 {
+		return fmt.Errorf("delegation should have positive value")
+	}
+	if tx.Source != nil && *tx.Source == *tx.Target {
+		return fmt.Errorf("can't delegate to self, use stake add instead")
+	}
+	return nil
+}
+
+// executeStakeDelegate moves the value from the balance of the delegator to the stake of the miner
+func executeStakeDelegate(db types.AccountDB, tx *types.Transaction) types.ReceiptStatus {
+	if err := validateStakeDelegation(tx); err != nil {
+		logger.Debugf("delegate %v fail:%v", tx.Hash.Hex(), err)
+		return types.RSFail
+	}
+	value := tx.Value.Value()
+	if !db.CanTransfer(*tx.Source, value) {
+		return types.RSBalanceNotEnough
+	}
+	miner, key := *tx.Target, keyOfDelegation(*tx.Source)
+	if !db.Exist(miner) {
+		db.CreateAccount(miner)
+	}
+	db.SubBalance(*tx.Source, value)
+	delegation := getAmount(db, miner, key)
+	setAmount(db, miner, key, delegation.Add(delegation, value))
+	total := getAmount(db, miner, KeyOfDelegatedAmount)
+	setAmount(db, miner, KeyOfDelegatedAmount, total.Add(total, value))
+	return types.RSSuccess
+}
+
+// executeStakeUndelegate takes back the value from the stake delegated to the miner.
+// The value goes to the withdrawal queue of the delegator and can be claimed after the unbonding period
+func executeStakeUndelegate(db types.AccountDB, tx *types.Transaction, height uint64) types.ReceiptStatus {
+	if err := validateStakeDelegation(tx); err != nil {
+		logger.Debugf("undelegate %v fail:%v", tx.Hash.Hex(), err)
+		return types.RSFail
+	}
+	value := tx.Value.Value()
+	miner, key := *tx.Target, keyOfDelegation(*tx.Source)
+	delegation := getAmount(db, miner, key)
+	if delegation.Cmp(value) < 0 {
+		return types.RSBalanceNotEnough
+	}
+	snapshot := db.Snapshot()
+	setAmount(db, miner, key, delegation.Sub(delegation, value))
+	total := getAmount(db, miner, KeyOfDelegatedAmount)
+	setAmount(db, miner, KeyOfDelegatedAmount, total.Sub(total, value))
+	if err := enqueueUnbonding(db, *tx.Source, value, height); err != nil {
+		logger.Debugf("undelegate %v fail:%v", tx.Hash.Hex(), err)
+		db.RevertToSnapshot(snapshot)
+		return types.RSFail
+	}
+	return types.RSSuccess
+}
@@ -0,0 +1,114 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+var testMiner = common.BytesToAddress([]byte("miner"))
+
+// newStakeTx returns a stake transaction of the value from the source to the target, which may be nil
+func newStakeTx(typ int8, source common.Address, target *common.Address, value uint64) *types.Transaction {
+	return newTypedTx(typ, source, 1, 500, func(tx *types.Transaction) {
+		tx.Target, tx.Value = target, types.NewBigInt(value)
+	})
+}
+
+func TestStakeDelegation_Validate(t *testing.T) {
+	delegator := common.BytesToAddress([]byte("delegator"))
+	if err := validateStakeDelegation(newStakeTx(types.TransactionTypeStakeDelegate, delegator, &testMiner, 100)); err != nil {
+		t.Fatalf("validate error:%v", err)
+	}
+	if err := validateStakeDelegation(newStakeTx(types.TransactionTypeStakeDelegate, delegator, &testMiner, 0)); err == nil {
+		t.Fatalf("zero value should be rejected")
+	}
+	if err := validateStakeDelegation(newStakeTx(types.TransactionTypeStakeDelegate, testMiner, &testMiner, 100)); err == nil {
+		t.Fatalf("delegating to self should be rejected")
+	}
+	tx := newStakeTx(types.TransactionTypeStakeUndelegate, delegator, &testMiner, 100)
+	tx.Target = nil
+	if err := validateStakeDelegation(tx); err == nil {
+		t.Fatalf("delegation without target should be rejected")
+	}
+}
+
+func TestStakeDelegation_Execute(t *testing.T) {
+	db := newTestAccountDB(t)
+	alice := common.BytesToAddress([]byte("alice"))
+	bob := common.BytesToAddress([]byte("bob"))
+	db.AddBalance(alice, big.NewInt(1000))
+	db.AddBalance(bob, big.NewInt(1000))
+	db.AddBalance(testMiner, big.NewInt(1))
+	db.SetData(testMiner, KeyOfstakeAmount, big.NewInt(500).Bytes())
+
+	if status := executeStakeDelegate(db, newStakeTx(types.TransactionTypeStakeDelegate, alice, &testMiner, 2000)); status != types.RSBalanceNotEnough {
+		t.Fatalf("delegated more than balance")
+	}
+	for _, tx := range []*types.Transaction{
+		newStakeTx(types.TransactionTypeStakeDelegate, alice, &testMiner, 300),
+		newStakeTx(types.TransactionTypeStakeDelegate, alice, &testMiner, 100),
+		newStakeTx(types.TransactionTypeStakeDelegate, bob, &testMiner, 200),
+	} {
+		if status := executeStakeDelegate(db, tx); status != types.RSSuccess {
+			t.Fatalf("delegate fail:%v", status)
+		}
+	}
+	if status := executeStakeUndelegate(db, newStakeTx(types.TransactionTypeStakeUndelegate, bob, &testMiner, 201), 10); status != types.RSBalanceNotEnough {
+		t.Fatalf("undelegated more than delegated")
+	}
+	if status := executeStakeUndelegate(db, newStakeTx(types.TransactionTypeStakeUndelegate, alice, &testMiner, 150), 10); status != types.RSSuccess {
+		t.Fatalf("undelegate fail:%v", status)
+	}
+	// The undelegated stake is not released until the unbonding period passes
+	if db.GetBalance(alice).Uint64() != 600 || db.GetBalance(bob).Uint64() != 800 {
+		t.Fatalf("unexpected balance, alice %v bob %v", db.GetBalance(alice), db.GetBalance(bob))
+	}
+	if q, _ := GetUnbondings(db, alice); len(q) != 1 || q[0].Amount.Uint64() != 150 || q[0].Maturity != 10+UnbondingPeriod {
+		t.Fatalf("unexpected unbondings of alice")
+	}
+	if pledge := (stakePledgeMgr{}).getPledge(db, testMiner); pledge != 950 {
+		t.Fatalf("unexpected pledge %v", pledge)
+	}
+
+	db = commitTestAccountDB(t, db)
+	detail := GetPledgeDetail(db, testMiner)
+	if detail.Own.Uint64() != 500 || detail.Delegated.Uint64() != 450 || detail.Total().Uint64() != 950 {
+		t.Fatalf("unexpected pledge detail, own %v delegated %v", detail.Own, detail.Delegated)
+	}
+	expect := map[common.Address]uint64{alice: 250, bob: 200}
+	if len(detail.Delegations) != len(expect) {
+		t.Fatalf("unexpected delegations %v", len(detail.Delegations))
+	}
+	for _, d := range detail.Delegations {
+		if expect[d.Delegator] != d.Amount.Uint64() {
+			t.Fatalf("unexpected delegation of %v: %v", d.Delegator.Hex(), d.Amount)
+		}
+	}
+
+	// Fully undelegated ones are removed from the list
+	if status := executeStakeUndelegate(db, newStakeTx(types.TransactionTypeStakeUndelegate, bob, &testMiner, 200), 10); status != types.RSSuccess {
+		t.Fatalf("undelegate fail:%v", status)
+	}
+	db = commitTestAccountDB(t, db)
+	if detail := GetPledgeDetail(db, testMiner); len(detail.Delegations) != 1 || detail.Delegations[0].Delegator != alice {
+		t.Fatalf("unexpected delegations after undelegating")
+	}
+}
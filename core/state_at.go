@@ -0,0 +1,68 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xchain/go-chain/global/types"
+	"github.com/xchain/go-chain/storage/account"
+	"github.com/xchain/go-chain/storage/trie"
+)
+
+// ErrStatePruned is returned when the state of the block has been removed from the disk in the full gc mode
+var ErrStatePruned = errors.New("state pruned")
+
+type stateHeaderReader interface {
+	QueryTopBlock() *types.BlockHeader
+	QueryBlockHeaderByHeight(height uint64) *types.BlockHeader
+}
+
+// StateDBAt returns the state of the block at the height, opened at the state root of the block header
+func (s *ChainState) StateDBAt(height uint64) (types.AccountDB, error) {
+	header := s.chain.QueryBlockHeaderByHeight(height)
+	if header == nil {
+		return nil, fmt.Errorf("block at %v not found", height)
+	}
+	return s.stateOf(header)
+}
+
+// LatestStateDB returns the state of the top block. The accounts are read from the flat state
+// instead of walking down the trie
+func (s *ChainState) LatestStateDB() (types.AccountDB, error) {
+	top := s.chain.QueryTopBlock()
+	if top == nil {
+		return nil, fmt.Errorf("top block not found")
+	}
+	return s.stateOf(top)
+}
+
+func (s *ChainState) stateOf(header *types.BlockHeader) (types.AccountDB, error) {
+	db, err := account.NewAccountDB(header.StateTree, s.db)
+	if err != nil {
+		return nil, stateError(err)
+	}
+	return db, nil
+}
+
+// stateError translates the missing trie node error to ErrStatePruned
+func stateError(err error) error {
+	if _, ok := err.(*trie.MissingNodeError); ok {
+		return ErrStatePruned
+	}
+	return err
+}
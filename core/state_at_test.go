@@ -0,0 +1,180 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+	"github.com/xchain/go-chain/storage/account"
+	"github.com/xchain/go-chain/storage/xchaindb"
+)
+
+type mockHeaderChain map[uint64]*types.BlockHeader
+
+func (m mockHeaderChain) QueryTopBlock() *types.BlockHeader {
+	var top *types.BlockHeader
+	for _, h := range m {
+		if top == nil || h.Height > top.Height {
+			top = h
+		}
+	}
+	return top
+}
+
+func (m mockHeaderChain) QueryBlockHeaderByHeight(height uint64) *types.BlockHeader {
+	return m[height]
+}
+
+func TestChainState_StateDBAt(t *testing.T) {
+	defer func(mode string, retention uint64) {
+		GCMode, StateRetention = mode, retention
+	}(GCMode, StateRetention)
+	GCMode, StateRetention = GCModeFull, 2
+
+	ds, clean := newTestDataSource(t)
+	defer clean()
+	chain := make(mockHeaderChain)
+	cs, err := NewChainState(ds, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := commitTestStates(t, cs, 6)
+	for h, root := range roots {
+		chain[uint64(h+1)] = &types.BlockHeader{Height: uint64(h + 1), StateTree: root}
+	}
+
+	for h := uint64(5); h <= 6; h++ {
+		db, err := cs.StateDBAt(h)
+		if err != nil {
+			t.Fatalf("open state at %v error:%v", h, err)
+		}
+		if db.GetBalance(common.BytesToAddress([]byte("alice"))).Uint64() != 10*h {
+			t.Fatalf("balance error at %v", h)
+		}
+	}
+	if _, err := cs.StateDBAt(1); err != ErrStatePruned {
+		t.Fatalf("state at 1 should be pruned, got %v", err)
+	}
+	if _, err := cs.StateDBAt(7); err == nil || err == ErrStatePruned {
+		t.Fatalf("state at 7 should be not found, got %v", err)
+	}
+}
+
+func TestChainState_FlatState(t *testing.T) {
+	ds, clean := newTestDataSource(t)
+	defer clean()
+	chain := make(mockHeaderChain)
+	cs, err := NewChainState(ds, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := commitTestStates(t, cs, 4)
+	for h, root := range roots {
+		chain[uint64(h+1)] = &types.BlockHeader{Height: uint64(h + 1), StateTree: root}
+	}
+
+	// The flat state is kept apart from the trie nodes
+	flatdb, _ := ds.NewPrefixDatabase(FlatStatePrefix)
+	iter := flatdb.NewIterator()
+	if !iter.Next() {
+		t.Fatalf("flat state should be written")
+	}
+	iter.Release()
+
+	// Reopened at the top block
+	cs, err = NewChainState(ds, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := cs.StateDBAt(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.GetBalance(common.BytesToAddress([]byte("alice"))).Uint64() != 40 {
+		t.Fatalf("balance error after reopen")
+	}
+
+	if db, err = cs.LatestStateDB(); err != nil {
+		t.Fatal(err)
+	}
+	if db.GetBalance(common.BytesToAddress([]byte("alice"))).Uint64() != 40 {
+		t.Fatalf("balance error of the latest state")
+	}
+}
+
+func TestImportState_Open(t *testing.T) {
+	defer func(backend string) { xchaindb.Backend = backend }(xchaindb.Backend)
+	for _, backend := range []string{xchaindb.BackendLevelDB, xchaindb.BackendBolt} {
+		xchaindb.Backend = backend
+		t.Run(backend, testImportStateOpen)
+	}
+}
+
+func testImportStateOpen(t *testing.T) {
+	ds, clean := newTestDataSource(t)
+	defer clean()
+	chain := make(mockHeaderChain)
+	cs, err := NewChainState(ds, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := commitTestStates(t, cs, 3)
+	top := &types.BlockHeader{Height: 3, Hash: common.BytesToHash([]byte("top")), StateTree: roots[2]}
+	chain[3] = top
+
+	db, err := cs.StateDBAt(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	_, err = account.ExportSnapshot(&buf, db.(*account.AccountDB), account.SnapshotHeader{
+		Height:    top.Height,
+		BlockHash: top.Hash,
+		StateTree: top.StateTree,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot isn't of the trusted root
+	untrusted, cleanUntrusted := newTestDataSource(t)
+	defer cleanUntrusted()
+	if _, _, err = ImportState(untrusted, bytes.NewReader(buf.Bytes()), roots[1]); err == nil {
+		t.Fatalf("snapshot of untrusted root imported")
+	}
+
+	// Import into a fresh database and open it as the chain does
+	fresh, cleanFresh := newTestDataSource(t)
+	defer cleanFresh()
+	header, _, err := ImportState(fresh, &buf, top.StateTree)
+	if err != nil {
+		t.Fatalf("import error:%v", err)
+	}
+	if header.StateTree != top.StateTree {
+		t.Fatalf("imported root error")
+	}
+	cs, err = NewChainState(fresh, mockHeaderChain{3: top})
+	if err != nil {
+		t.Fatalf("open imported state error:%v", err)
+	}
+	checkTestState(t, cs.AccountDatabase(), top.StateTree, 3)
+	if _, err = cs.StateDBAt(3); err != nil {
+		t.Fatalf("open imported state at 3 error:%v", err)
+	}
+}
@@ -0,0 +1,132 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/account"
+	"github.com/xchain/go-chain/storage/trie"
+)
+
+const (
+	GCModeFull    = "full"    // Only the states of the recent blocks are kept on the disk
+	GCModeArchive = "archive" // All the historical states are kept on the disk
+
+	DefaultGCMode         = GCModeArchive
+	DefaultStateRetention = 128
+)
+
+var (
+	// GCMode decides if the historical states are pruned
+	GCMode = DefaultGCMode
+
+	// StateRetention is the number of the recent block states kept in the full gc mode.
+	// It should be larger than the depth of the forks the chain may switch to
+	StateRetention uint64 = DefaultStateRetention
+)
+
+// ValidGCMode checks if the gc mode is supported
+func ValidGCMode(mode string) bool {
+	return mode == GCModeFull || mode == GCModeArchive
+}
+
+// stateCacheLimit is the memory allowance of the dirty trie nodes in the full gc mode, above which
+// the oldest nodes are flushed to the disk
+const stateCacheLimit common.StorageSize = 256 * 1024 * 1024
+
+type stateRoot struct {
+	height uint64
+	root   common.Hash
+}
+
+// statePruner keeps the states of the recent retention blocks referenced in the trie database and
+// dereferences the older ones, so the nodes only used by them are garbage-collected from the memory
+// before they are ever written to the disk. The nodes flushed by the memory cap are swept from the
+// disk every retention blocks, and the top state is written on close
+type statePruner struct {
+	triedb     *trie.NodeDatabase
+	retention  uint64
+	cacheLimit common.StorageSize
+	roots      []stateRoot // The referenced states, in the ascending order of height
+	flushed    bool        // If any node is flushed to the disk since the last sweep
+	lastSweep  uint64
+}
+
+// newStatePruner returns the pruner of the configured gc mode, or nil in the archive mode
+func newStatePruner(triedb *trie.NodeDatabase) *statePruner {
+	if GCMode != GCModeFull || StateRetention == 0 {
+		return nil
+	}
+	return &statePruner{
+		triedb:     triedb,
+		retention:  StateRetention,
+		cacheLimit: stateCacheLimit,
+		roots:      make([]stateRoot, 0, StateRetention+1),
+	}
+}
+
+// commit is called after the state of the block is committed to the trie database. The states of the
+// blocks switched out by a fork are dereferenced. It must not be called concurrently with committing
+// the states
+func (p *statePruner) commit(height uint64, root common.Hash) error {
+	for len(p.roots) > 0 && p.roots[len(p.roots)-1].height >= height {
+		p.triedb.Dereference(p.roots[len(p.roots)-1].root)
+		p.roots = p.roots[:len(p.roots)-1]
+	}
+	p.triedb.Reference(root, common.Hash{})
+	p.roots = append(p.roots, stateRoot{height: height, root: root})
+
+	for uint64(len(p.roots)) > p.retention {
+		p.triedb.Dereference(p.roots[0].root)
+		p.roots = p.roots[1:]
+	}
+	if nodes, _ := p.triedb.Size(); nodes > p.cacheLimit {
+		if err := p.triedb.Cap(p.cacheLimit); err != nil {
+			logger.Errorf("flush state at %v error:%v", height, err)
+			return err
+		}
+		p.flushed = true
+	}
+	if p.flushed && height >= p.lastSweep+p.retention {
+		return p.sweep(height)
+	}
+	return nil
+}
+
+// sweep deletes the flushed nodes not reachable from the referenced states from the disk
+func (p *statePruner) sweep(height uint64) error {
+	roots := make([]common.Hash, len(p.roots))
+	for i, r := range p.roots {
+		roots[i] = r.root
+	}
+	kept, pruned, err := p.triedb.Prune(roots, account.AccountLeafChildren)
+	if err != nil {
+		logger.Errorf("prune state at %v error:%v", height, err)
+		return err
+	}
+	p.flushed = false
+	p.lastSweep = height
+	logger.Infof("state pruned at %v, from %v, kept %v nodes, pruned %v nodes", height, p.roots[0].height, kept, pruned)
+	return nil
+}
+
+// close writes the top state to the disk, so the node can restart from it
+func (p *statePruner) close() error {
+	if len(p.roots) == 0 {
+		return nil
+	}
+	return p.triedb.Commit(p.roots[len(p.roots)-1].root, false)
+}
@@ -0,0 +1,175 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/account"
+)
+
+// commitTestStates commits the states of the blocks from 1 to the height and returns their roots
+func commitTestStates(t *testing.T, cs *ChainState, blocks uint64) []common.Hash {
+	alice := common.BytesToAddress([]byte("alice"))
+	bob := common.BytesToAddress([]byte("bob"))
+	key := []byte("counter")
+
+	roots := make([]common.Hash, 0, blocks)
+	root := common.Hash{}
+	for h := uint64(1); h <= blocks; h++ {
+		state, err := account.NewAccountDB(root, cs.AccountDatabase())
+		if err != nil {
+			t.Fatalf("open state at %v error:%v", h-1, err)
+		}
+		state.AddBalance(alice, big.NewInt(10))
+		state.AddBalance(bob, big.NewInt(1))
+		state.SetData(alice, key, []byte{byte(h)})
+		if root, err = cs.Commit(h, state); err != nil {
+			t.Fatalf("commit at %v error:%v", h, err)
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// checkTestState checks the state committed by commitTestStates at the height
+func checkTestState(t *testing.T, db account.AccountDatabase, root common.Hash, h uint64) {
+	alice := common.BytesToAddress([]byte("alice"))
+	bob := common.BytesToAddress([]byte("bob"))
+
+	state, err := account.NewAccountDB(root, db)
+	if err != nil {
+		t.Fatalf("open state at %v error:%v", h, err)
+	}
+	if state.GetBalance(alice).Uint64() != 10*h || state.GetBalance(bob).Uint64() != h {
+		t.Fatalf("balance error at %v", h)
+	}
+	if !bytes.Equal(state.GetData(alice, []byte("counter")), []byte{byte(h)}) {
+		t.Fatalf("data error at %v", h)
+	}
+}
+
+func TestChainState_Archive(t *testing.T) {
+	if GCMode != GCModeArchive {
+		t.Fatalf("default gc mode should be archive")
+	}
+	ds, clean := newTestDataSource(t)
+	defer clean()
+	cs, err := NewChainState(ds, mockHeaderChain{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.pruner != nil {
+		t.Fatalf("pruner should be nil in archive mode")
+	}
+	roots := commitTestStates(t, cs, 8)
+
+	// All the states are on the disk
+	db, _ := ds.NewPrefixDatabase(StateDBPrefix)
+	for h := uint64(1); h <= 8; h++ {
+		checkTestState(t, account.NewDatabase(db), roots[h-1], h)
+	}
+}
+
+func testStatePruner(t *testing.T, cacheLimit common.StorageSize) {
+	defer func(mode string, retention uint64) {
+		GCMode, StateRetention = mode, retention
+	}(GCMode, StateRetention)
+	GCMode, StateRetention = GCModeFull, 4
+
+	ds, clean := newTestDataSource(t)
+	defer clean()
+	cs, err := NewChainState(ds, mockHeaderChain{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.pruner == nil {
+		t.Fatalf("pruner should be created in full mode")
+	}
+	cs.pruner.cacheLimit = cacheLimit
+
+	const blocks = 20
+	roots := commitTestStates(t, cs, blocks)
+
+	// The recent states are complete
+	for h := uint64(blocks - StateRetention + 1); h <= blocks; h++ {
+		checkTestState(t, cs.AccountDatabase(), roots[h-1], h)
+	}
+	// The old states are gone
+	for h := uint64(1); h <= blocks-StateRetention; h++ {
+		if _, err := account.NewAccountDB(roots[h-1], cs.AccountDatabase()); stateError(err) != ErrStatePruned {
+			t.Fatalf("state at %v should be pruned, got %v", h, err)
+		}
+	}
+
+	// Only the top state is on the disk after close
+	if err := cs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, _ := ds.NewPrefixDatabase(StateDBPrefix)
+	disk := account.NewDatabase(db)
+	checkTestState(t, disk, roots[blocks-1], blocks)
+	for h := uint64(1); h <= blocks-2*StateRetention; h++ {
+		if _, err := account.NewAccountDB(roots[h-1], disk); stateError(err) != ErrStatePruned {
+			t.Fatalf("state at %v should be pruned from the disk, got %v", h, err)
+		}
+	}
+}
+
+func TestStatePruner_InMemory(t *testing.T) {
+	testStatePruner(t, stateCacheLimit)
+}
+
+func TestStatePruner_Flushed(t *testing.T) {
+	// Every node is flushed to the disk on commit, and swept when out of the window
+	testStatePruner(t, 0)
+}
+
+func TestStatePruner_Reorg(t *testing.T) {
+	defer func(mode string, retention uint64) {
+		GCMode, StateRetention = mode, retention
+	}(GCMode, StateRetention)
+	GCMode, StateRetention = GCModeFull, 4
+
+	ds, clean := newTestDataSource(t)
+	defer clean()
+	cs, err := NewChainState(ds, mockHeaderChain{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := commitTestStates(t, cs, 3)
+
+	// Switch to a fork at height 2
+	state, _ := account.NewAccountDB(roots[0], cs.AccountDatabase())
+	state.AddBalance(common.BytesToAddress([]byte("carol")), big.NewInt(1))
+	fork, err := cs.Commit(2, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := cs.pruner
+	if len(p.roots) != 2 || p.roots[1].root != fork {
+		t.Fatalf("the states switched out should be replaced, got %v", p.roots)
+	}
+	for _, root := range roots[1:] {
+		if _, err := account.NewAccountDB(root, cs.AccountDatabase()); stateError(err) != ErrStatePruned {
+			t.Fatalf("state switched out should be dereferenced, got %v", err)
+		}
+	}
+	checkTestState(t, cs.AccountDatabase(), roots[0], 1)
+}
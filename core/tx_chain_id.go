@@ -0,0 +1,53 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/xchain/go-chain/global/types"
+)
+
+var (
+	// ChainID is the id of the chain this node runs on, transactions signed for other chains are rejected
+	ChainID uint16
+
+	// ChainIDForkHeight is the height of the first block from which the transactions must be signed with the chain id.
+	// The legacy ones without chain id are still accepted before it. It's disabled unless configured
+	ChainIDForkHeight uint64 = math.MaxUint64
+)
+
+// ChainIDForkEnabled reports whether the height rejecting the transactions without chain id is configured
+func ChainIDForkEnabled() bool {
+	return ChainIDForkHeight != math.MaxUint64
+}
+
+// chainIDActivated reports whether the replay protection is activated for the block of the given height
+func chainIDActivated(height uint64) bool {
+	return height >= ChainIDForkHeight
+}
+
+// validateTxChainID checks whether the transaction can be packed into the block of the given height on this chain
+func validateTxChainID(tx *types.Transaction, height uint64) error {
+	if tx.ChainID != 0 && tx.ChainID != ChainID {
+		return fmt.Errorf("transaction signed for chain %v, expect %v", tx.ChainID, ChainID)
+	}
+	if tx.ChainID == 0 && chainIDActivated(height) {
+		return fmt.Errorf("transaction without chain id is not accepted after height %v", ChainIDForkHeight)
+	}
+	return nil
+}
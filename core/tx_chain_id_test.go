@@ -0,0 +1,122 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/crypto"
+	"github.com/xchain/go-chain/global/types"
+)
+
+func setChainID(chainID uint16, forkHeight uint64) func() {
+	oldID, oldFork := ChainID, ChainIDForkHeight
+	ChainID, ChainIDForkHeight = chainID, forkHeight
+	return func() {
+		ChainID, ChainIDForkHeight = oldID, oldFork
+	}
+}
+
+func newChainTx(source common.Address, nonce uint64, chainID uint16) *types.Transaction {
+	return newTypedTx(types.TransactionTypeTransfer, source, nonce, 1000, func(tx *types.Transaction) {
+		tx.ChainID = chainID
+	})
+}
+
+func TestTxChainID_Validate(t *testing.T) {
+	defer setChainID(2, 100)()
+	source := common.BytesToAddress([]byte("chain"))
+
+	cases := []struct {
+		chainID uint16
+		height  uint64
+		valid   bool
+	}{
+		{0, 99, true}, // legacy transaction before fork
+		{0, 100, false},
+		{2, 99, true},
+		{2, 100, true},
+		{3, 99, false}, // foreign chain is rejected even before fork
+		{3, 100, false},
+	}
+	for i, cs := range cases {
+		err := validateTxChainID(newChainTx(source, 1, cs.chainID), cs.height)
+		if (err == nil) != cs.valid {
+			t.Fatalf("case %v: unexpected validation result %v", i, err)
+		}
+	}
+}
+
+func TestTxChainID_ForkDisabled(t *testing.T) {
+	if ChainIDForkEnabled() {
+		t.Fatalf("chain id fork should be disabled unless configured")
+	}
+	defer setChainID(0, math.MaxUint64)()
+	source := common.BytesToAddress([]byte("chain"))
+	if err := validateTxChainID(newChainTx(source, 1, 0), 1<<40); err != nil {
+		t.Fatalf("legacy transaction rejected without fork:%v", err)
+	}
+	if err := validateTxChainID(newChainTx(source, 1, 2), 1); err == nil {
+		t.Fatalf("transaction signed for other chain accepted")
+	}
+}
+
+func TestTxChainID_Replay(t *testing.T) {
+	sk, _ := crypto.GenerateKey("")
+	tx := newSignedTx(sk, 1, 1000)
+	tx.ChainID = 1
+	tx.Hash = tx.GenHash()
+	sign, _ := sk.Sign(tx.Hash.Bytes())
+	tx.Sign = &sign
+
+	// Replaying the signed transaction on another chain changes the hash, so the sign recovers to another source
+	replayed := *tx
+	replayed.ChainID = 2
+	replayed.Hash = replayed.GenHash()
+	replayed.Source = nil
+	if err := replayed.RecoverSource(); err == nil && *replayed.Source == sk.GetPubKey().GetAddress() {
+		t.Fatalf("signature replayed on another chain")
+	}
+	legacy := newChainTx(sk.GetPubKey().GetAddress(), 1, 0)
+	if legacy.Hash == tx.Hash {
+		t.Fatalf("chain id should be covered by the hash")
+	}
+}
+
+func TestTxChainID_Pack(t *testing.T) {
+	defer setChainID(2, 10)()
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	c.push(newChainTx(common.BytesToAddress([]byte("legacy")), 1, 0))
+	c.push(newChainTx(common.BytesToAddress([]byte("protected")), 1, 2))
+	// Foreign transactions are rejected on admission
+	if err := c.push(newChainTx(common.BytesToAddress([]byte("foreign")), 1, 3)); err == nil {
+		t.Fatalf("foreign tx should be rejected")
+	}
+
+	if txs := c.pack(PackByGasPrice, 9, 1000000, DefaultBlockSizeForPackage); len(txs) != 2 {
+		t.Fatalf("unexpected packed count before fork %v", len(txs))
+	}
+	if txs := c.pack(PackByGasPrice, 10, 1000000, DefaultBlockSizeForPackage); len(txs) != 1 || txs[0].ChainID != 2 {
+		t.Fatalf("unexpected packed txs after fork %v", len(txs))
+	}
+	// Legacy transactions are rejected on admission once the next block is after the fork
+	c.onBlockAdded(9)
+	if err := c.push(newChainTx(common.BytesToAddress([]byte("late")), 1, 0)); err == nil {
+		t.Fatalf("legacy tx should be rejected after fork")
+	}
+}
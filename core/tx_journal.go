@@ -0,0 +1,150 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/vmihailenco/msgpack"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global"
+	"github.com/xchain/go-chain/global/types"
+	"github.com/xchain/go-chain/storage/xchaindb"
+)
+
+const (
+	txJournalPrefix = "txjournal"
+
+	txJournalRotateRoutine  = "tx_journal_rotate"
+	txJournalRotateInterval = 600 // Rotate the journal every 10 minutes
+)
+
+// txJournal is the persistent store of the locally submitted transactions,
+// so that they can be recovered into the pool after the node restarts
+type txJournal struct {
+	db xchaindb.Database
+}
+
+func newTxJournal(ds *xchaindb.XchainDataSource) (*txJournal, error) {
+	db, err := ds.NewPrefixDatabase(txJournalPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &txJournal{db: db}, nil
+}
+
+// insert records the transaction into the journal
+func (j *txJournal) insert(tx *types.Transaction) error {
+	bs, err := msgpack.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	return j.db.Put(tx.Hash.Bytes(), bs)
+}
+
+// load reads all the transactions in the journal and calls add for each of them.
+// Transactions fail to decode or add are deleted from the journal
+func (j *txJournal) load(add func(tx *types.Transaction) error) (loaded int, dropped int) {
+	iter := j.db.NewIterator()
+	defer iter.Release()
+
+	batch := j.db.NewBatch()
+	for iter.Next() {
+		tx := new(types.Transaction)
+		if err := msgpack.Unmarshal(iter.Value(), tx); err != nil {
+			logger.Warnf("decode journal tx %x error:%v", iter.Key(), err)
+			batch.Delete(common.CopyBytes(iter.Key()))
+			dropped++
+			continue
+		}
+		// Source must be recovered from the sign again
+		tx.Source = nil
+		if err := add(tx); err != nil {
+			logger.Debugf("drop journal tx %v:%v", tx.Hash.Hex(), err)
+			batch.Delete(common.CopyBytes(iter.Key()))
+			dropped++
+			continue
+		}
+		loaded++
+	}
+	if err := batch.Write(); err != nil {
+		logger.Errorf("clean journal error:%v", err)
+	}
+	return
+}
+
+// rotate removes the transactions which are no longer alive from the journal,
+// e.g. included in a block or evicted from the pool
+func (j *txJournal) rotate(alive func(hash common.Hash) bool) (kept int, dropped int, err error) {
+	iter := j.db.NewIterator()
+	defer iter.Release()
+
+	batch := j.db.NewBatch()
+	for iter.Next() {
+		if alive(common.BytesToHash(iter.Key())) {
+			kept++
+			continue
+		}
+		batch.Delete(common.CopyBytes(iter.Key()))
+		dropped++
+	}
+	err = batch.Write()
+	return
+}
+
+// replay loads the journaled transactions back into the container as local ones.
+// Each transaction has its sign checked and goes through the same admission as the newly submitted ones
+func (j *txJournal) replay(c *simpleContainer) (loaded int, dropped int) {
+	db := c.chain.LatestStateDB()
+	loaded, dropped = j.load(func(tx *types.Transaction) error {
+		if err := RecoverTxSource(db, tx); err != nil {
+			return err
+		}
+		return c.add(tx, true)
+	})
+	// Journal is iterated in hash order, so the higher nonce ones may be queued before the lower ones arrive
+	c.promoteQueueToPending()
+	return
+}
+
+// initTxJournal opens the journal of the container, replays the transactions recorded before the restart
+// and registers the rotation routine on the global ticker
+func initTxJournal(ds *xchaindb.XchainDataSource, c *simpleContainer) error {
+	journal, err := newTxJournal(ds)
+	if err != nil {
+		return err
+	}
+	loaded, dropped := journal.replay(c)
+	logger.Infof("tx journal replayed, loaded %v, dropped %v", loaded, dropped)
+
+	c.journal = journal
+
+	ctx := global.Context()
+	if ctx == nil || ctx.Ticker == nil {
+		return nil
+	}
+	ticker := ctx.Ticker
+	ticker.RegisterPeriodicRoutine(txJournalRotateRoutine, func() bool {
+		kept, dropped, err := journal.rotate(c.contains)
+		if err != nil {
+			logger.Errorf("rotate tx journal error:%v", err)
+			return false
+		}
+		logger.Debugf("tx journal rotated, kept %v, dropped %v", kept, dropped)
+		return true
+	}, txJournalRotateInterval)
+	ticker.StartTickerRoutine(txJournalRotateRoutine, false)
+	return nil
+}
@@ -0,0 +1,171 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xchain/go-chain/crypto"
+	"github.com/xchain/go-chain/global/types"
+	"github.com/xchain/go-chain/storage/xchaindb"
+)
+
+func newSignedTx(sk crypto.PrivateKey, nonce uint64, gasPrice uint64) *types.Transaction {
+	source := sk.GetPubKey().GetAddress()
+	tx := newMockTx(source, nonce, gasPrice)
+	sign, _ := sk.Sign(tx.Hash.Bytes())
+	tx.Sign = &sign
+	return tx
+}
+
+func newTestDataSource(t *testing.T) (*xchaindb.XchainDataSource, func()) {
+	dir, err := ioutil.TempDir("", "xchaindb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := xchaindb.NewDataSource(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ds, func() {
+		db, _ := ds.NewPrefixDatabase("")
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func journalSize(journal *txJournal) int {
+	iter := journal.db.NewIterator()
+	defer iter.Release()
+	size := 0
+	for iter.Next() {
+		size++
+	}
+	return size
+}
+
+func TestTxJournal_Replay(t *testing.T) {
+	ds, clean := newTestDataSource(t)
+	defer clean()
+	sk, _ := crypto.GenerateKey("")
+	source := sk.GetPubKey().GetAddress()
+
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, ds)
+	for nonce := uint64(1); nonce <= 5; nonce++ {
+		if err := c.pushLocal(newSignedTx(sk, nonce, 1000*nonce)); err != nil {
+			t.Fatalf("push local error:%v", err)
+		}
+	}
+	if size := journalSize(c.journal); size != 5 {
+		t.Fatalf("unexpected journal size %v", size)
+	}
+
+	// simulate the restart after the first two are included, they can't pass the admission again
+	recovered := newSimpleContainer(1000, 1000, mockNonceReader{source: 2}, ds)
+	if pending, _ := recovered.sizes(); pending != 3 || journalSize(recovered.journal) != 3 {
+		t.Fatalf("unexpected size, pending %v journal %v", pending, journalSize(recovered.journal))
+	}
+	recovered.eachForPack(func(tx *types.Transaction) bool {
+		if *tx.Source != source {
+			t.Fatalf("source not recovered")
+		}
+		if !recovered.locals.contains(tx) {
+			t.Fatalf("replayed tx %v is not local", tx.Nonce)
+		}
+		return true
+	})
+}
+
+func TestTxJournal_ReplayAtChainHeight(t *testing.T) {
+	ds, clean := newTestDataSource(t)
+	defer clean()
+	sk, _ := crypto.GenerateKey("")
+
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, ds)
+	txs := make([]*types.Transaction, 0)
+	for nonce := uint64(1); nonce <= 2; nonce++ {
+		tx := newMockTx(sk.GetPubKey().GetAddress(), nonce, 1000)
+		tx.ValidUntil = 10 * nonce
+		tx.Hash = tx.GenHash()
+		sign, _ := sk.Sign(tx.Hash.Bytes())
+		tx.Sign = &sign
+		if err := c.pushLocal(tx); err != nil {
+			t.Fatalf("push local error:%v", err)
+		}
+		txs = append(txs, tx)
+	}
+
+	// the node restarts with the chain at height 10, before any block event arrives
+	recovered := newSimpleContainer(1000, 1000, mockHeightChain{mockNonceReader{}, 10}, ds)
+	if recovered.contains(txs[0].Hash) || !recovered.contains(txs[1].Hash) || journalSize(recovered.journal) != 1 {
+		t.Fatalf("tx expired at the chain height replayed, size %v journal %v", recovered.Len(), journalSize(recovered.journal))
+	}
+	if atomic.LoadUint64(&recovered.height) != 10 {
+		t.Fatalf("unexpected pool height %v", recovered.height)
+	}
+}
+
+func TestTxJournal_ReplayDropsUnsigned(t *testing.T) {
+	ds, clean := newTestDataSource(t)
+	defer clean()
+	sk, _ := crypto.GenerateKey("")
+
+	journal, err := newTxJournal(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := newSignedTx(sk, 1, 1000)
+	journal.insert(tx)
+	// the source can't be recovered without the sign
+	forged := newSignedTx(sk, 2, 1000)
+	forged.Sign = nil
+	journal.insert(forged)
+
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	loaded, dropped := journal.replay(c)
+	if loaded != 1 || dropped != 1 || !c.contains(tx.Hash) || c.contains(forged.Hash) {
+		t.Fatalf("unexpected replay result, loaded %v dropped %v", loaded, dropped)
+	}
+}
+
+func TestTxJournal_Rotate(t *testing.T) {
+	ds, clean := newTestDataSource(t)
+	defer clean()
+	sk, _ := crypto.GenerateKey("")
+
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, ds)
+	journal := c.journal
+	txs := make([]*types.Transaction, 0)
+	for nonce := uint64(1); nonce <= 4; nonce++ {
+		tx := newSignedTx(sk, nonce, 1000)
+		c.pushLocal(tx)
+		txs = append(txs, tx)
+	}
+	// the first two are included in block
+	c.remove(txs[0].Hash)
+	c.remove(txs[1].Hash)
+
+	kept, dropped, err := journal.rotate(c.contains)
+	if err != nil {
+		t.Fatalf("rotate error:%v", err)
+	}
+	if kept != 2 || dropped != 2 || journalSize(journal) != 2 {
+		t.Fatalf("unexpected rotate result, kept %v dropped %v size %v", kept, dropped, journalSize(journal))
+	}
+}
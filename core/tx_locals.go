@@ -0,0 +1,173 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+	"github.com/xchain/go-chain/middleware/notify"
+	"github.com/xchain/go-chain/middleware/ticker"
+)
+
+// DefaultTxLocalLimit is the default value of TxLocalLimit
+const DefaultTxLocalLimit = 1024
+
+// TxLocalLimit is the max number of local transactions in the pool. Local transactions are exempted from
+// price based eviction and queue limits, so they need a separate cap
+var TxLocalLimit = DefaultTxLocalLimit
+
+const (
+	txRebroadcastRoutine  = "tx_rebroadcast"
+	txRebroadcastInterval = 60 // Rebroadcast the local transactions every minute
+)
+
+var errLocalFull = fmt.Errorf("tx pool local slots are used up")
+
+// txLocals tracks the transactions submitted through the rpc of this node
+type txLocals struct {
+	limit int
+	txs   map[common.Hash]*types.Transaction
+	lock  sync.RWMutex
+}
+
+func newTxLocals(limit int) *txLocals {
+	return &txLocals{
+		limit: limit,
+		txs:   make(map[common.Hash]*types.Transaction),
+	}
+}
+
+// add marks the transaction as local. Replacing a local one with the same nonce doesn't take more slot
+func (l *txLocals) add(tx *types.Transaction) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if _, ok := l.txs[tx.Hash]; ok {
+		return nil
+	}
+	if len(l.txs) >= l.limit && !l.replacing(tx) {
+		return errLocalFull
+	}
+	l.txs[tx.Hash] = tx
+	return nil
+}
+
+func (l *txLocals) replacing(tx *types.Transaction) bool {
+	for _, local := range l.txs {
+		if local.Nonce == tx.Nonce && *local.Source == *tx.Source {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *txLocals) contains(tx *types.Transaction) bool {
+	if l == nil {
+		return false
+	}
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	_, ok := l.txs[tx.Hash]
+	return ok
+}
+
+func (l *txLocals) remove(tx *types.Transaction) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.txs, tx.Hash)
+}
+
+func (l *txLocals) list() []*types.Transaction {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	txs := make([]*types.Transaction, 0, len(l.txs))
+	for _, tx := range l.txs {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// localTxs returns the local transactions still in the pool
+func (c *simpleContainer) localTxs() []*types.Transaction {
+	return c.locals.list()
+}
+
+// rebroadcast publishes the local transactions to the bus, so that the network sends them to the peers again
+func (c *simpleContainer) rebroadcast(bus *notify.Bus) (int, error) {
+	txs := c.localTxs()
+	if len(txs) == 0 {
+		return 0, nil
+	}
+	msg, err := types.NewTxBroadcastMessage(txs)
+	if err != nil {
+		return 0, err
+	}
+	bus.Publish(notify.TxBroadcast, msg)
+	return len(txs), nil
+}
+
+// startRebroadcastRoutine registers the periodic rebroadcast of the local transactions on the given ticker.
+// Local transactions leave the pool once included, so they are sent again and again until then
+func (c *simpleContainer) startRebroadcastRoutine(gt *ticker.GlobalTicker, bus *notify.Bus) {
+	gt.RegisterPeriodicRoutine(txRebroadcastRoutine, func() bool {
+		n, err := c.rebroadcast(bus)
+		if err != nil {
+			logger.Warnf("rebroadcast local txs error:%v", err)
+			return false
+		}
+		if n > 0 {
+			logger.Debugf("rebroadcast %v local txs", n)
+		}
+		return true
+	}, txRebroadcastInterval)
+	gt.StartTickerRoutine(txRebroadcastRoutine, false)
+}
+
+// addAnnounced adds the transactions announced by the peers as the remote ones, and returns the number
+// of the transactions added. The hash is checked against the content, so the sign can't be moved onto others
+func (c *simpleContainer) addAnnounced(txs []*types.Transaction) int {
+	added := 0
+	for _, tx := range txs {
+		if tx == nil || tx.Hash != tx.GenHash() {
+			continue
+		}
+		if err := tx.RecoverSource(); err != nil {
+			continue
+		}
+		if err := c.push(tx); err != nil {
+			logger.Debugf("add announced tx %v error:%v", tx.Hash.Hex(), err)
+			continue
+		}
+		added++
+	}
+	return added
+}
+
+// startAnnounceRoutine follows the transactions the peers announce, e.g. the local ones they rebroadcast
+func (c *simpleContainer) startAnnounceRoutine(bus *notify.Bus) {
+	bus.Subscribe(notify.TxAnnounce, func(message notify.Message) {
+		txs, err := types.UnMarshalTransactions(types.AsDefault(message).Body())
+		if err != nil {
+			logger.Debugf("unmarshal announced txs error:%v", err)
+			return
+		}
+		c.addAnnounced(txs)
+	})
+}
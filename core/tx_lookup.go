@@ -0,0 +1,120 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+const txLookupShards = 16
+
+type txLookupShard struct {
+	lock      sync.RWMutex
+	txs       map[common.Hash]*types.Transaction
+	firstSeen map[common.Hash]time.Time // The time each transaction first arrives the pool
+}
+
+// txLookup is the index of all the transactions in the pool by hash. It is sharded by hash with its own locks,
+// so that the lookups from tx syncing and rpc don't contend with the container lock
+type txLookup struct {
+	shards [txLookupShards]*txLookupShard
+}
+
+func newTxLookup() *txLookup {
+	l := &txLookup{}
+	for i := range l.shards {
+		l.shards[i] = &txLookupShard{
+			txs:       make(map[common.Hash]*types.Transaction),
+			firstSeen: make(map[common.Hash]time.Time),
+		}
+	}
+	return l
+}
+
+func (l *txLookup) shard(hash common.Hash) *txLookupShard {
+	return l.shards[hash[0]%txLookupShards]
+}
+
+func (l *txLookup) get(hash common.Hash) *types.Transaction {
+	s := l.shard(hash)
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.txs[hash]
+}
+
+func (l *txLookup) contains(hash common.Hash) bool {
+	return l.get(hash) != nil
+}
+
+func (l *txLookup) add(tx *types.Transaction, seen time.Time) {
+	s := l.shard(tx.Hash)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.txs[tx.Hash] = tx
+	s.firstSeen[tx.Hash] = seen
+}
+
+func (l *txLookup) remove(hash common.Hash) {
+	s := l.shard(hash)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.txs, hash)
+	delete(s.firstSeen, hash)
+}
+
+func (l *txLookup) len() int {
+	size := 0
+	for _, s := range l.shards {
+		s.lock.RLock()
+		size += len(s.txs)
+		s.lock.RUnlock()
+	}
+	return size
+}
+
+// expired returns the transactions first seen longer than lifetime before now
+func (l *txLookup) expired(now time.Time, lifetime time.Duration) []*types.Transaction {
+	txs := make([]*types.Transaction, 0)
+	for _, s := range l.shards {
+		s.lock.RLock()
+		for hash, seen := range s.firstSeen {
+			if now.Sub(seen) >= lifetime {
+				txs = append(txs, s.txs[hash])
+			}
+		}
+		s.lock.RUnlock()
+	}
+	return txs
+}
+
+// filter returns the transactions which f reports true
+func (l *txLookup) filter(f func(tx *types.Transaction) bool) []*types.Transaction {
+	txs := make([]*types.Transaction, 0)
+	for _, s := range l.shards {
+		s.lock.RLock()
+		for _, tx := range s.txs {
+			if f(tx) {
+				txs = append(txs, tx)
+			}
+		}
+		s.lock.RUnlock()
+	}
+	return txs
+}
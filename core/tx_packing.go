@@ -0,0 +1,107 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"sync/atomic"
+
+	"github.com/xchain/go-chain/global/types"
+)
+
+const (
+	PackByGasPrice   = "gas_price"    // Pack the transactions with higher gas price first
+	PackByFeePerByte = "fee_per_byte" // Pack the transactions with higher fee per byte first
+
+	DefaultPackingStrategy     = PackByGasPrice
+	DefaultBlockSizeForPackage = 1024 * 1024 // Max total size of the transactions packed into a block
+)
+
+var (
+	TxPackingStrategy   = DefaultPackingStrategy
+	BlockSizeForPackage = DefaultBlockSizeForPackage
+)
+
+// packingStrategies maps the strategy names to the orders of the pending transactions.
+// The less function reports whether a should be packed before b
+var packingStrategies = map[string]func(a, b *types.Transaction) bool{
+	PackByGasPrice:   gasPriceLess,
+	PackByFeePerByte: feePerByteLess,
+}
+
+// ValidPackingStrategy checks if the given packing strategy is supported
+func ValidPackingStrategy(name string) bool {
+	_, ok := packingStrategies[name]
+	return ok
+}
+
+func gasPriceLess(a, b *types.Transaction) bool {
+	return a.GasPrice.Cmp(b.GasPrice.Value()) > 0
+}
+
+// effectiveFee returns the max fee the transaction pays for being packed
+func effectiveFee(tx *types.Transaction) *big.Int {
+	return new(big.Int).Mul(tx.GasPrice.Value(), tx.GasLimit.Value())
+}
+
+// feePerByteLess compares the fee per byte by cross multiplying, so no precision is lost.
+// Transactions with the same fee per byte are ordered by gas price
+func feePerByteLess(a, b *types.Transaction) bool {
+	left := effectiveFee(a)
+	left.Mul(left, big.NewInt(int64(b.Size())))
+	right := effectiveFee(b)
+	right.Mul(right, big.NewInt(int64(a.Size())))
+	if cmp := left.Cmp(right); cmp != 0 {
+		return cmp > 0
+	}
+	return gasPriceLess(a, b)
+}
+
+// pack selects the pending transactions for the block of the given height in the order of the given strategy.
+// Transactions exceeding the rest gas or size of the block, out of their validity windows or not for this chain are skipped,
+// as well as the following ones of the same source, and the smaller ones after them still have chance to be packed
+func (c *simpleContainer) pack(strategy string, height uint64, gasLimit uint64, sizeLimit int) []*types.Transaction {
+	less, ok := packingStrategies[strategy]
+	if !ok {
+		logger.Warnf("unknown packing strategy %v, fallback to %v", strategy, PackByGasPrice)
+		less = gasPriceLess
+	}
+	var (
+		txs     = make([]*types.Transaction, 0)
+		gasUsed uint64
+		size    int
+	)
+	c.eachForPackBy(less, func(tx *types.Transaction) (packed bool, next bool) {
+		gas := tx.GasLimit.Uint64()
+		if gasUsed+gas > gasLimit || size+tx.Size() > sizeLimit {
+			return false, true
+		}
+		if tx.CheckValidHeight(height) != nil || validateTxChainID(tx, height) != nil {
+			return false, true
+		}
+		gasUsed += gas
+		size += tx.Size()
+		txs = append(txs, tx)
+		return true, true
+	})
+	return txs
+}
+
+// PackForCast selects the transactions for casting the block next to the latest one on chain with the configured
+// strategy and limits. It's what the pool returns to the proposer for casting a block
+func (c *simpleContainer) PackForCast() []*types.Transaction {
+	return c.pack(TxPackingStrategy, atomic.LoadUint64(&c.height)+1, GasLimitForPackage, BlockSizeForPackage)
+}
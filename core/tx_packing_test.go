@@ -0,0 +1,178 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+func newPackingTx(source common.Address, nonce uint64, gasPrice uint64, gasLimit uint64, dataSize int) *types.Transaction {
+	return newTypedTx(types.TransactionTypeTransfer, source, nonce, gasPrice, func(tx *types.Transaction) {
+		tx.GasLimit = types.NewBigInt(gasLimit)
+		tx.Data = make([]byte, dataSize)
+	})
+}
+
+func packingRevenue(txs []*types.Transaction) *big.Int {
+	revenue := new(big.Int)
+	for _, tx := range txs {
+		revenue.Add(revenue, effectiveFee(tx))
+	}
+	return revenue
+}
+
+// checkPacked checks the packed transactions are within the limits and in the nonce order of each source
+func checkPacked(t *testing.T, txs []*types.Transaction, gasLimit uint64, sizeLimit int) {
+	var (
+		gasUsed uint64
+		size    int
+		nonces  = make(map[common.Address]uint64)
+	)
+	for _, tx := range txs {
+		gasUsed += tx.GasLimit.Uint64()
+		size += tx.Size()
+		if last, ok := nonces[*tx.Source]; ok && tx.Nonce != last+1 {
+			t.Fatalf("nonce gap of %v: %v after %v", tx.Source.Hex(), tx.Nonce, last)
+		}
+		nonces[*tx.Source] = tx.Nonce
+	}
+	if gasUsed > gasLimit || size > sizeLimit {
+		t.Fatalf("limit exceeded, gas %v size %v", gasUsed, size)
+	}
+}
+
+func TestPacking_SizeBound(t *testing.T) {
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	for i := 0; i < 50; i++ {
+		// Large transactions paying high gas price but little per byte
+		c.push(newPackingTx(common.BytesToAddress([]byte{'f', byte(i)}), 1, 300, 3000, 2000))
+		// Small transactions paying lower gas price but much more per byte
+		c.push(newPackingTx(common.BytesToAddress([]byte{'t', byte(i)}), 1, 200, 3000, 0))
+	}
+	gasLimit, sizeLimit := uint64(1000000), 20000
+
+	byPrice := c.pack(PackByGasPrice, 1, gasLimit, sizeLimit)
+	byFee := c.pack(PackByFeePerByte, 1, gasLimit, sizeLimit)
+	checkPacked(t, byPrice, gasLimit, sizeLimit)
+	checkPacked(t, byFee, gasLimit, sizeLimit)
+
+	priceRevenue, feeRevenue := packingRevenue(byPrice), packingRevenue(byFee)
+	t.Logf("gas price packed %v revenue %v, fee per byte packed %v revenue %v", len(byPrice), priceRevenue, len(byFee), feeRevenue)
+	if len(byPrice) != 10 || len(byFee) != 54 {
+		t.Fatalf("unexpected packed count %v %v", len(byPrice), len(byFee))
+	}
+	if feeRevenue.Cmp(priceRevenue) <= 0 {
+		t.Fatalf("fee per byte should earn more when the block size is the limit")
+	}
+}
+
+func TestPacking_GasBound(t *testing.T) {
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	for i := 0; i < 100; i++ {
+		c.push(newPackingTx(common.BytesToAddress([]byte{byte(i)}), 1, uint64(100+i), 3000, 100))
+	}
+	gasLimit, sizeLimit := uint64(3000*20), DefaultBlockSizeForPackage
+
+	// Same size for all, both strategies should pack the 20 highest priced ones
+	byPrice := c.pack(PackByGasPrice, 1, gasLimit, sizeLimit)
+	byFee := c.pack(PackByFeePerByte, 1, gasLimit, sizeLimit)
+	checkPacked(t, byPrice, gasLimit, sizeLimit)
+	checkPacked(t, byFee, gasLimit, sizeLimit)
+	if len(byPrice) != 20 || len(byFee) != 20 {
+		t.Fatalf("unexpected packed count %v %v", len(byPrice), len(byFee))
+	}
+	if packingRevenue(byPrice).Cmp(packingRevenue(byFee)) != 0 {
+		t.Fatalf("revenue should be the same")
+	}
+	for _, tx := range byPrice {
+		if tx.GasPrice.Uint64() < 180 {
+			t.Fatalf("lower priced tx packed: %v", tx.GasPrice.Uint64())
+		}
+	}
+}
+
+func TestPacking_SkipSourceNotFit(t *testing.T) {
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	large := common.BytesToAddress([]byte("large"))
+	c.push(newPackingTx(large, 1, 1000, 3000, 0))
+	c.push(newPackingTx(large, 2, 1000, 3000, 5000))
+	c.push(newPackingTx(large, 3, 1000, 3000, 0))
+	small := common.BytesToAddress([]byte("small"))
+	c.push(newPackingTx(small, 1, 100, 3000, 0))
+
+	txs := c.pack(PackByGasPrice, 1, 1000000, 1000)
+	checkPacked(t, txs, 1000000, 1000)
+	// nonce 2 of large doesn't fit, so nonce 3 can't be packed while the small one still can
+	if len(txs) != 2 || txs[0].Nonce != 1 || *txs[1].Source != small {
+		t.Fatalf("unexpected packed txs %v", len(txs))
+	}
+}
+
+func TestPacking_RandomPools(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	gasLimit, sizeLimit := uint64(3000*300), 100*1024
+	for round := 0; round < 5; round++ {
+		c := newSimpleContainer(10000, 10000, mockNonceReader{}, nil)
+		for i := 0; i < 200; i++ {
+			source := common.BytesToAddress([]byte{byte(round), byte(i)})
+			for nonce := uint64(1); nonce <= uint64(1+r.Intn(3)); nonce++ {
+				c.push(newPackingTx(source, nonce, uint64(100+r.Intn(1000)), uint64(3000+r.Intn(3000)), r.Intn(2000)))
+			}
+		}
+		byPrice := c.pack(PackByGasPrice, 1, gasLimit, sizeLimit)
+		byFee := c.pack(PackByFeePerByte, 1, gasLimit, sizeLimit)
+		checkPacked(t, byPrice, gasLimit, sizeLimit)
+		checkPacked(t, byFee, gasLimit, sizeLimit)
+		t.Logf("round %v: gas price packed %v revenue %v, fee per byte packed %v revenue %v",
+			round, len(byPrice), packingRevenue(byPrice), len(byFee), packingRevenue(byFee))
+	}
+}
+
+func TestPacking_UnknownStrategy(t *testing.T) {
+	if ValidPackingStrategy("unknown") || !ValidPackingStrategy(PackByFeePerByte) {
+		t.Fatalf("unexpected strategy validation")
+	}
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	c.push(newPackingTx(common.BytesToAddress([]byte("a")), 1, 100, 3000, 0))
+	if txs := c.pack("unknown", 1, 1000000, 1000); len(txs) != 1 {
+		t.Fatalf("should fallback to the default strategy")
+	}
+}
+
+func TestPacking_PackForCast(t *testing.T) {
+	defer func(strategy string, gasLimit uint64) {
+		TxPackingStrategy, GasLimitForPackage = strategy, gasLimit
+	}(TxPackingStrategy, GasLimitForPackage)
+	TxPackingStrategy, GasLimitForPackage = PackByFeePerByte, 1000000
+
+	c := newSimpleContainer(1000, 1000, mockHeightChain{mockNonceReader{}, 9}, nil)
+	c.push(newWindowTx(common.BytesToAddress([]byte("next")), 1, 10, 0))
+	c.push(newWindowTx(common.BytesToAddress([]byte("later")), 1, 11, 0))
+	// Packed for the block next to the chain height
+	txs := c.PackForCast()
+	if len(txs) != 1 || txs[0].ValidFrom != 10 {
+		t.Fatalf("unexpected packed txs %v", len(txs))
+	}
+	c.onBlockAdded(10)
+	if txs := c.PackForCast(); len(txs) != 2 {
+		t.Fatalf("unexpected packed txs %v", len(txs))
+	}
+}
@@ -0,0 +1,70 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+type balanceReader interface {
+	GetBalance(address common.Address) *big.Int
+}
+
+// validateSponsoredTx checks the sign of the fee payer, and the balances of the sender and the payer.
+// The payer pays the max gas fee and the sender pays the value
+func validateSponsoredTx(br balanceReader, tx *types.Transaction) error {
+	if tx.Sponsor == nil {
+		return nil
+	}
+	if err := tx.VerifySponsor(); err != nil {
+		return err
+	}
+	if tx.Source != nil && *tx.Source == tx.Sponsor.Payer {
+		return fmt.Errorf("fee payer should not be the sender")
+	}
+	if balance := br.GetBalance(tx.Sponsor.Payer); balance == nil || balance.Cmp(tx.GasCost()) < 0 {
+		return fmt.Errorf("fee payer balance not enough for gas %v", tx.GasCost())
+	}
+	if balance := br.GetBalance(*tx.Source); tx.Value != nil && (balance == nil || balance.Cmp(tx.Value.Value()) < 0) {
+		return fmt.Errorf("sender balance not enough for value %v", tx.Value.Value())
+	}
+	return nil
+}
+
+// chargeGas deducts the max gas fee from the gas payer before execution and returns the payer
+func chargeGas(db types.AccountDB, tx *types.Transaction) (*common.Address, error) {
+	payer := tx.GasPayer()
+	cost := tx.GasCost()
+	if !db.CanTransfer(*payer, cost) {
+		return payer, fmt.Errorf("balance of %v not enough for gas %v", payer.Hex(), cost)
+	}
+	db.SubBalance(*payer, cost)
+	return payer, nil
+}
+
+// refundGas returns the fee of the unused gas to the gas payer after execution
+func refundGas(db types.AccountDB, tx *types.Transaction, gasUsed uint64) {
+	limit := tx.GasLimit.Uint64()
+	if gasUsed >= limit {
+		return
+	}
+	refund := new(big.Int).Mul(new(big.Int).SetUint64(limit-gasUsed), tx.GasPrice.Value())
+	db.AddBalance(*tx.GasPayer(), refund)
+}
@@ -0,0 +1,117 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/crypto"
+	"github.com/xchain/go-chain/global/types"
+)
+
+type mockBalanceReader struct {
+	balances map[common.Address]*big.Int
+}
+
+func (m *mockBalanceReader) GetBalance(address common.Address) *big.Int {
+	if b, ok := m.balances[address]; ok {
+		return b
+	}
+	return new(big.Int)
+}
+
+// newSponsoredTx returns a transaction signed by the sender and the payer. Gas fee is 3000*gasPrice
+func newSponsoredTx(sender, payer crypto.PrivateKey, nonce uint64, gasPrice uint64) *types.Transaction {
+	tx := newTypedTx(types.TransactionTypeTransfer, sender.GetPubKey().GetAddress(), nonce, gasPrice, func(tx *types.Transaction) {
+		tx.Sponsor = &types.Sponsor{Payer: payer.GetPubKey().GetAddress()}
+	})
+	sign, _ := sender.Sign(tx.Hash.Bytes())
+	tx.Sign = &sign
+	payerSign, _ := payer.Sign(tx.Hash.Bytes())
+	tx.Sponsor.Sign = &payerSign
+	return tx
+}
+
+func TestSponsor_Validate(t *testing.T) {
+	sender, _ := crypto.GenerateKey("")
+	payer, _ := crypto.GenerateKey("")
+	stranger, _ := crypto.GenerateKey("")
+	br := &mockBalanceReader{balances: map[common.Address]*big.Int{
+		payer.GetPubKey().GetAddress():  big.NewInt(3000 * 100),
+		sender.GetPubKey().GetAddress(): big.NewInt(1),
+	}}
+
+	tx := newSponsoredTx(sender, payer, 1, 100)
+	if err := validateSponsoredTx(br, tx); err != nil {
+		t.Fatalf("valid sponsored tx rejected:%v", err)
+	}
+	if *tx.GasPayer() != payer.GetPubKey().GetAddress() {
+		t.Fatalf("gas should be paid by the payer")
+	}
+
+	// The payer can't afford the higher gas price
+	if err := validateSponsoredTx(br, newSponsoredTx(sender, payer, 1, 101)); err == nil {
+		t.Fatalf("payer balance not checked")
+	}
+	// Signed by someone else rather than the payer
+	forged := newSponsoredTx(sender, payer, 1, 100)
+	forgedSign, _ := stranger.Sign(forged.Hash.Bytes())
+	forged.Sponsor.Sign = &forgedSign
+	if err := validateSponsoredTx(br, forged); err == nil {
+		t.Fatalf("forged payer sign accepted")
+	}
+	// The payer is covered by the hash, so the sender sign can't be moved to another payer
+	moved := newSponsoredTx(sender, payer, 1, 100)
+	moved.Sponsor.Payer = stranger.GetPubKey().GetAddress()
+	moved.Hash = moved.GenHash()
+	moved.Source = nil
+	if err := moved.RecoverSource(); err == nil && *moved.Source == sender.GetPubKey().GetAddress() {
+		t.Fatalf("sender sign reused for another payer")
+	}
+	// The value is still paid by the sender
+	valued := newMockTx(sender.GetPubKey().GetAddress(), 1, 100)
+	valued.Value = types.NewBigInt(2)
+	valued.Sponsor = &types.Sponsor{Payer: payer.GetPubKey().GetAddress()}
+	valued.Hash = valued.GenHash()
+	payerSign, _ := payer.Sign(valued.Hash.Bytes())
+	valued.Sponsor.Sign = &payerSign
+	if err := validateSponsoredTx(br, valued); err == nil {
+		t.Fatalf("sender balance not checked")
+	}
+}
+
+func TestSponsor_ChargeGas(t *testing.T) {
+	db := newTestAccountDB(t)
+	sender, _ := crypto.GenerateKey("")
+	payer, _ := crypto.GenerateKey("")
+	source, payerAddr := sender.GetPubKey().GetAddress(), payer.GetPubKey().GetAddress()
+	db.AddBalance(payerAddr, big.NewInt(1000000))
+
+	tx := newSponsoredTx(sender, payer, 1, 100)
+	paid, err := chargeGas(db, tx)
+	if err != nil || *paid != payerAddr {
+		t.Fatalf("charge gas error:%v", err)
+	}
+	refundGas(db, tx, 1000)
+	if db.GetBalance(payerAddr).Uint64() != 1000000-1000*100 {
+		t.Fatalf("unexpected payer balance %v", db.GetBalance(payerAddr))
+	}
+	if db.GetBalance(source).Sign() != 0 {
+		t.Fatalf("sender should pay nothing for gas")
+	}
+}
@@ -0,0 +1,49 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/xchain/go-chain/global/types"
+)
+
+// TxValidFromAhead is the max number of blocks the valid from height of a transaction can be ahead of the chain,
+// so that the pool won't be filled with the ones can't be packed in a long time
+const TxValidFromAhead = 1000
+
+// validateTxWindow checks the validity window of the transaction against the height of the chain.
+// The transaction should be able to be packed into one of the following blocks
+func validateTxWindow(tx *types.Transaction, height uint64) error {
+	if tx.ValidUntil != 0 && tx.ValidFrom > tx.ValidUntil {
+		return types.ErrTxInvalidWindow
+	}
+	if tx.ExpiredAt(height) {
+		return types.ErrTxWindowExpired
+	}
+	if tx.ValidFrom > height+1+TxValidFromAhead {
+		return fmt.Errorf("transaction valid from %v is too far ahead of the chain height %v", tx.ValidFrom, height)
+	}
+	return nil
+}
+
+// evictOutOfWindow removes the transactions whose validity window ends before the block next to the given height.
+// Local transactions are also removed since they can never be packed
+func (c *simpleContainer) evictOutOfWindow(height uint64) (pending, queued []*types.Transaction) {
+	return c.evict(c.lookup.filter(func(tx *types.Transaction) bool {
+		return tx.ExpiredAt(height)
+	}), true, &c.outOfWindowPending, &c.outOfWindowQueued)
+}
@@ -0,0 +1,109 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+func newWindowTx(source common.Address, nonce uint64, validFrom, validUntil uint64) *types.Transaction {
+	return newTypedTx(types.TransactionTypeTransfer, source, nonce, 1000, func(tx *types.Transaction) {
+		tx.ValidFrom, tx.ValidUntil = validFrom, validUntil
+	})
+}
+
+func TestTxWindow_Validate(t *testing.T) {
+	source := common.BytesToAddress([]byte("window"))
+	cases := []struct {
+		validFrom, validUntil uint64
+		valid                 bool
+	}{
+		{0, 0, true},
+		{0, 101, true},
+		{0, 100, false}, // can't be packed into the next block 101
+		{50, 40, false},
+		{101 + TxValidFromAhead, 0, true},
+		{102 + TxValidFromAhead, 0, false},
+	}
+	for i, cs := range cases {
+		err := validateTxWindow(newWindowTx(source, 1, cs.validFrom, cs.validUntil), 100)
+		if (err == nil) != cs.valid {
+			t.Fatalf("case %v: unexpected validation result %v", i, err)
+		}
+	}
+}
+
+func TestTxWindow_Evict(t *testing.T) {
+	source := common.BytesToAddress([]byte("window"))
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	c.push(newWindowTx(source, 1, 0, 0))
+	c.push(newWindowTx(source, 2, 0, 10))
+	c.push(newWindowTx(source, 3, 0, 0))
+	c.push(newWindowTx(source, 5, 0, 10))
+	local := newWindowTx(common.BytesToAddress([]byte("local")), 1, 0, 10)
+	c.pushLocal(local)
+
+	if pending, queued := c.onBlockAdded(9); len(pending) != 0 || len(queued) != 0 {
+		t.Fatalf("evicted before expired")
+	}
+	pending, queued := c.onBlockAdded(10)
+	if len(pending) != 2 || len(queued) != 1 {
+		t.Fatalf("unexpected evicted pending %v queued %v", len(pending), len(queued))
+	}
+	// Counted apart from the lifetime expiry
+	if status := c.Status(); status.OutOfWindowPending != 2 || status.OutOfWindowQueued != 1 || status.ExpiredPending != 0 || status.ExpiredQueued != 0 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	// Nonce 3 is moved back to queue since nonce 2 is evicted
+	if p, q := c.sizes(); p != 1 || q != 1 {
+		t.Fatalf("unexpected size, pending %v queued %v", p, q)
+	}
+	if c.contains(local.Hash) {
+		t.Fatalf("local tx out of window should be evicted")
+	}
+
+	// The pool rejects the transactions expired at the current height
+	if err := c.push(newWindowTx(source, 2, 0, 10)); err != types.ErrTxWindowExpired {
+		t.Fatalf("expired tx should be rejected, got %v", err)
+	}
+	if err := c.push(newWindowTx(source, 2, 0, 11)); err != nil {
+		t.Fatalf("valid tx rejected:%v", err)
+	}
+	if err := c.push(newWindowTx(source, 4, 12+TxValidFromAhead, 0)); err == nil {
+		t.Fatalf("tx valid too far ahead should be rejected")
+	}
+}
+
+func TestTxWindow_Pack(t *testing.T) {
+	source := common.BytesToAddress([]byte("window"))
+	c := newSimpleContainer(1000, 1000, mockNonceReader{}, nil)
+	c.push(newWindowTx(source, 1, 0, 0))
+	c.push(newWindowTx(source, 2, 20, 0))
+	c.push(newWindowTx(source, 3, 0, 0))
+	other := common.BytesToAddress([]byte("other"))
+	c.push(newWindowTx(other, 1, 0, 0))
+
+	// Nonce 2 is not valid yet, so nonce 3 has to wait either
+	if txs := c.pack(PackByGasPrice, 10, 1000000, DefaultBlockSizeForPackage); len(txs) != 2 {
+		t.Fatalf("unexpected packed count %v", len(txs))
+	}
+	if txs := c.pack(PackByGasPrice, 20, 1000000, DefaultBlockSizeForPackage); len(txs) != 4 {
+		t.Fatalf("unexpected packed count %v", len(txs))
+	}
+}
@@ -0,0 +1,128 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/global/types"
+)
+
+const (
+	DefaultUnbondingPeriod = 10000 // Blocks the reduced stake stays locked before it can be claimed
+	MaxUnbondingEntries    = 32    // Max pending unbondings of an account
+)
+
+// UnbondingPeriod is the number of blocks from the reducing till the stake can be claimed
+var UnbondingPeriod uint64 = DefaultUnbondingPeriod
+
+// keyOfUnbondingQueue is the data key of the withdrawal queue of the account.
+// The queue is stored as a whole so that the changes in the same block are always visible
+var keyOfUnbondingQueue = []byte("unbonding_queue")
+
+// GetUnbondings returns the pending unbondings of the account, ordered by the maturity height
+func GetUnbondings(db types.AccountDB, addr common.Address) (types.UnbondingQueue, error) {
+	data := db.GetData(addr, keyOfUnbondingQueue)
+	if len(data) == 0 {
+		return types.UnbondingQueue{}, nil
+	}
+	return types.DecodeUnbondingQueue(data)
+}
+
+func putUnbondings(db types.AccountDB, addr common.Address, q types.UnbondingQueue) error {
+	if len(q) == 0 {
+		db.RemoveData(addr, keyOfUnbondingQueue)
+		return nil
+	}
+	data, err := types.EncodeUnbondingQueue(q)
+	if err != nil {
+		return err
+	}
+	db.SetData(addr, keyOfUnbondingQueue, data)
+	return nil
+}
+
+// enqueueUnbonding appends the amount to the withdrawal queue of the account, which matures
+// after the unbonding period. Amounts maturing at the same height are merged
+func enqueueUnbonding(db types.AccountDB, addr common.Address, amount *big.Int, height uint64) error {
+	q, err := GetUnbondings(db, addr)
+	if err != nil {
+		return err
+	}
+	maturity := height + UnbondingPeriod
+	if n := len(q); n > 0 && q[n-1].Maturity == maturity {
+		q[n-1].Amount = &types.BigInt{Int: *new(big.Int).Add(q[n-1].Amount.Value(), amount)}
+	} else {
+		if n >= MaxUnbondingEntries {
+			return fmt.Errorf("too many pending unbondings, claim the matured ones first")
+		}
+		q = append(q, &types.Unbonding{Amount: &types.BigInt{Int: *new(big.Int).Set(amount)}, Maturity: maturity})
+	}
+	return putUnbondings(db, addr, q)
+}
+
+// executeStakeReduce reduces the stake of the source and puts it into the withdrawal queue,
+// instead of releasing it to the balance immediately
+func executeStakeReduce(db types.AccountDB, tx *types.Transaction, height uint64) types.ReceiptStatus {
+	value := tx.Value.Value()
+	if value.Sign() <= 0 {
+		return types.RSFail
+	}
+	staker := *tx.Source
+	stake := getAmount(db, staker, KeyOfstakeAmount)
+	if stake.Cmp(value) < 0 {
+		return types.RSBalanceNotEnough
+	}
+	snapshot := db.Snapshot()
+	setAmount(db, staker, KeyOfstakeAmount, stake.Sub(stake, value))
+	if err := enqueueUnbonding(db, staker, value, height); err != nil {
+		logger.Debugf("stake reduce %v fail:%v", tx.Hash.Hex(), err)
+		db.RevertToSnapshot(snapshot)
+		return types.RSFail
+	}
+	return types.RSSuccess
+}
+
+// validateStakeClaim checks the claiming transaction before it enters the pool
+func validateStakeClaim(tx *types.Transaction) error {
+	if tx.Target != nil {
+		return fmt.Errorf("stake claim should not have target")
+	}
+	if tx.Value != nil && tx.Value.Sign() != 0 {
+		return fmt.Errorf("stake claim should not have value")
+	}
+	return nil
+}
+
+// executeStakeClaim releases all the matured unbondings of the source to its balance
+func executeStakeClaim(db types.AccountDB, tx *types.Transaction, height uint64) types.ReceiptStatus {
+	q, err := GetUnbondings(db, *tx.Source)
+	if err != nil {
+		logger.Debugf("stake claim %v parse fail:%v", tx.Hash.Hex(), err)
+		return types.RSParseFail
+	}
+	matured, pending := q.Matured(height)
+	if matured.Sign() == 0 {
+		return types.RSFail
+	}
+	if err := putUnbondings(db, *tx.Source, pending); err != nil {
+		return types.RSFail
+	}
+	db.AddBalance(*tx.Source, matured)
+	return types.RSSuccess
+}
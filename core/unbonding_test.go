@@ -0,0 +1,97 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/global/types"
+)
+
+func TestUnbonding_ReduceAndClaim(t *testing.T) {
+	defer func(period uint64) { UnbondingPeriod = period }(UnbondingPeriod)
+	UnbondingPeriod = 100
+
+	db := newTestAccountDB(t)
+	db.AddBalance(testMiner, big.NewInt(1))
+	db.SetData(testMiner, KeyOfstakeAmount, big.NewInt(1000).Bytes())
+
+	if status := executeStakeReduce(db, newStakeTx(types.TransactionTypeStakeReduce, testMiner, nil, 1001), 10); status != types.RSBalanceNotEnough {
+		t.Fatalf("reduced more than stake")
+	}
+	for _, height := range []uint64{10, 10, 50} {
+		if status := executeStakeReduce(db, newStakeTx(types.TransactionTypeStakeReduce, testMiner, nil, 200), height); status != types.RSSuccess {
+			t.Fatalf("reduce fail:%v", status)
+		}
+	}
+	if stake := getAmount(db, testMiner, KeyOfstakeAmount); stake.Uint64() != 400 {
+		t.Fatalf("unexpected stake %v", stake)
+	}
+	if db.GetBalance(testMiner).Uint64() != 1 {
+		t.Fatalf("reduced stake released immediately")
+	}
+	q, err := GetUnbondings(db, testMiner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The ones reduced in the same block are merged
+	if len(q) != 2 || q[0].Amount.Uint64() != 400 || q[0].Maturity != 110 || q[1].Maturity != 150 {
+		t.Fatalf("unexpected unbonding queue")
+	}
+
+	claim := newStakeTx(types.TransactionTypeStakeClaim, testMiner, nil, 0)
+	if err := validateStakeClaim(claim); err != nil {
+		t.Fatalf("validate error:%v", err)
+	}
+	if status := executeStakeClaim(db, claim, 109); status != types.RSFail {
+		t.Fatalf("claimed before maturity")
+	}
+	if status := executeStakeClaim(db, claim, 110); status != types.RSSuccess {
+		t.Fatalf("claim fail:%v", status)
+	}
+	if db.GetBalance(testMiner).Uint64() != 401 {
+		t.Fatalf("unexpected balance %v", db.GetBalance(testMiner))
+	}
+	// Claimed ones can't be claimed again
+	if status := executeStakeClaim(db, claim, 110); status != types.RSFail {
+		t.Fatalf("claimed twice")
+	}
+	if status := executeStakeClaim(db, claim, 200); status != types.RSSuccess {
+		t.Fatalf("claim fail:%v", status)
+	}
+	if q, _ := GetUnbondings(db, testMiner); len(q) != 0 || db.GetBalance(testMiner).Uint64() != 601 {
+		t.Fatalf("unexpected state after claiming all")
+	}
+}
+
+func TestUnbonding_QueueLimit(t *testing.T) {
+	db := newTestAccountDB(t)
+	db.AddBalance(testMiner, big.NewInt(1))
+	db.SetData(testMiner, KeyOfstakeAmount, big.NewInt(1000).Bytes())
+	for i := uint64(0); i < MaxUnbondingEntries; i++ {
+		if status := executeStakeReduce(db, newStakeTx(types.TransactionTypeStakeReduce, testMiner, nil, 1), i); status != types.RSSuccess {
+			t.Fatalf("reduce fail:%v", status)
+		}
+	}
+	if status := executeStakeReduce(db, newStakeTx(types.TransactionTypeStakeReduce, testMiner, nil, 1), MaxUnbondingEntries); status != types.RSFail {
+		t.Fatalf("queue limit exceeded")
+	}
+	// The failed one is reverted
+	if stake := getAmount(db, testMiner, KeyOfstakeAmount); stake.Uint64() != 1000-MaxUnbondingEntries {
+		t.Fatalf("unexpected stake %v", stake)
+	}
+}
@@ -0,0 +1,78 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/vmihailenco/msgpack"
+
+	"github.com/xchain/go-chain/common"
+)
+
+// MaxBatchTransferItems is the max number of transfers in one batch transfer transaction
+const MaxBatchTransferItems = 500
+
+// TransferItem is one transfer of the batch transfer transaction
+type TransferItem struct {
+	Target common.Address `msgpack:"tg"`
+	Value  *BigInt        `msgpack:"v"`
+}
+
+// EncodeBatchTransfer encodes the transfers into the data of a batch transfer transaction
+func EncodeBatchTransfer(items []*TransferItem) ([]byte, error) {
+	if err := checkTransferItems(items); err != nil {
+		return nil, err
+	}
+	return msgpack.Marshal(items)
+}
+
+// DecodeBatchTransfer decodes the transfers from the data of a batch transfer transaction
+func DecodeBatchTransfer(data []byte) ([]*TransferItem, error) {
+	items := make([]*TransferItem, 0)
+	if err := msgpack.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("decode batch transfer error:%v", err)
+	}
+	if err := checkTransferItems(items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// BatchTransferTotal returns the total value of the transfers
+func BatchTransferTotal(items []*TransferItem) *big.Int {
+	total := new(big.Int)
+	for _, item := range items {
+		total.Add(total, item.Value.Value())
+	}
+	return total
+}
+
+func checkTransferItems(items []*TransferItem) error {
+	if len(items) == 0 {
+		return fmt.Errorf("empty batch transfer")
+	}
+	if len(items) > MaxBatchTransferItems {
+		return fmt.Errorf("too many transfers: %v, max %v", len(items), MaxBatchTransferItems)
+	}
+	for i, item := range items {
+		if item == nil || item.Value == nil || item.Value.Sign() <= 0 {
+			return fmt.Errorf("transfer %v has no positive value", i)
+		}
+	}
+	return nil
+}
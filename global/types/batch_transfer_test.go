@@ -0,0 +1,62 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+)
+
+func TestBatchTransfer_EncodeDecode(t *testing.T) {
+	items := []*TransferItem{
+		{Target: common.BytesToAddress([]byte("a")), Value: NewBigInt(100)},
+		{Target: common.BytesToAddress([]byte("b")), Value: NewBigInt(200)},
+	}
+	data, err := EncodeBatchTransfer(items)
+	if err != nil {
+		t.Fatalf("encode error:%v", err)
+	}
+	decoded, err := DecodeBatchTransfer(data)
+	if err != nil {
+		t.Fatalf("decode error:%v", err)
+	}
+	if len(decoded) != 2 || decoded[1].Target != items[1].Target || decoded[1].Value.Uint64() != 200 {
+		t.Fatalf("decoded transfers not match")
+	}
+	if total := BatchTransferTotal(decoded); total.Uint64() != 300 {
+		t.Fatalf("unexpected total %v", total)
+	}
+}
+
+func TestBatchTransfer_Invalid(t *testing.T) {
+	if _, err := EncodeBatchTransfer(nil); err == nil {
+		t.Fatalf("empty batch should fail")
+	}
+	if _, err := EncodeBatchTransfer([]*TransferItem{{Target: common.BytesToAddress([]byte("a")), Value: NewBigInt(0)}}); err == nil {
+		t.Fatalf("zero value should fail")
+	}
+	items := make([]*TransferItem, MaxBatchTransferItems+1)
+	for i := range items {
+		items[i] = &TransferItem{Value: NewBigInt(1)}
+	}
+	if _, err := EncodeBatchTransfer(items); err == nil {
+		t.Fatalf("too many transfers should fail")
+	}
+	if _, err := DecodeBatchTransfer([]byte("garbage")); err == nil {
+		t.Fatalf("garbage data should fail")
+	}
+}
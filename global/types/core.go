@@ -18,6 +18,7 @@ package types
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -33,12 +34,20 @@ const (
 
 // Supported transaction types
 const (
-	TransactionTypeTransfer      = 0
-	TransactionTypeBindUMID      = 1
-	TransactionTypeTransformUMID = 2
-	TransactionTypeUnbindUMID    = 3
-	TransactionTypeStakeAdd      = 4
-	TransactionTypeStakeReduce   = 5
+	TransactionTypeTransfer        = 0
+	TransactionTypeBindUMID        = 1
+	TransactionTypeTransformUMID   = 2
+	TransactionTypeUnbindUMID      = 3
+	TransactionTypeStakeAdd        = 4
+	TransactionTypeStakeReduce     = 5
+	TransactionTypeBatchTransfer   = 6  // Transfers to multiple targets, listed in the data
+	TransactionTypeMultisigCreate  = 7  // Creates a multisig account with the policy in the data
+	TransactionTypeHTLCLock        = 8  // Locks the value for the target under the hash lock in the data
+	TransactionTypeHTLCClaim       = 9  // Claims the locked value with the preimage in the data
+	TransactionTypeHTLCRefund      = 10 // Refunds the locked value to the sender after timeout
+	TransactionTypeStakeDelegate   = 11 // Delegates the value to the target miner as stake
+	TransactionTypeStakeUndelegate = 12 // Takes back the value delegated to the target miner
+	TransactionTypeStakeClaim      = 13 // Claims the matured unbondings of the reduced or undelegated stake
 )
 
 // Transaction denotes one transaction infos
@@ -55,13 +64,73 @@ type Transaction struct {
 
 	Sign   *crypto.Sign    `msgpack:"si"`  // The Sign of the sender
 	Source *common.Address `msgpack:"src"` // Sender address, recovered from sign
+
+	MultiSign *MultiSign `msgpack:"ms,omitempty"` // Signatures of the owners if sent from a multisig account
+
+	ValidFrom  uint64 `msgpack:"vf,omitempty"` // The lowest height of the block the transaction can be packed into, 0 for no limit
+	ValidUntil uint64 `msgpack:"vu,omitempty"` // The highest height of the block the transaction can be packed into, 0 for no limit
+
+	ChainID uint16 `msgpack:"cid,omitempty"` // The chain the transaction is signed for, 0 for the legacy ones without replay protection
+
+	Sponsor *Sponsor `msgpack:"sp,omitempty"` // The fee payer if the gas is paid by another account
 }
 
-// GenHash generate unique hash of the transaction. source,sign is out of the hash calculation range
+// Sponsor is the account paying the gas of the transaction for the sender
+type Sponsor struct {
+	Payer common.Address `msgpack:"pa"`
+	Sign  *crypto.Sign   `msgpack:"si"` // Sign of the payer on the transaction hash
+}
+
+var (
+	ErrTxNotValidYet   = errors.New("transaction not valid yet")
+	ErrTxWindowExpired = errors.New("transaction validity window expired")
+	ErrTxInvalidWindow = errors.New("transaction valid from height is higher than valid until")
+
+	ErrTxChainIDRequired = errors.New("transaction with multisig, validity window or sponsor should be signed with chain id")
+)
+
+// txHashVersion is the version of the layout the transactions with chain id are hashed in
+const txHashVersion = 1
+
+// GenHash generate unique hash of the transaction. source,sign is out of the hash calculation range.
+// Transactions without chain id keep the legacy hash, which doesn't cover the fields added since then,
+// and the ones with chain id are hashed with every field in a fixed layout
 func (tx *Transaction) GenHash() common.Hash {
 	if nil == tx {
 		return common.Hash{}
 	}
+	if tx.ChainID == 0 {
+		return tx.legacyHash()
+	}
+	buffer := bytes.Buffer{}
+	buffer.WriteByte(txHashVersion)
+	buffer.Write(common.UInt16ToByte(tx.ChainID))
+	buffer.WriteByte(byte(tx.Type))
+	buffer.Write(common.Uint64ToByte(tx.Nonce))
+	writeHashAddress(&buffer, tx.Target)
+	writeHashBytes(&buffer, tx.Value.GetBytesWithSign())
+	writeHashBytes(&buffer, tx.GasLimit.GetBytesWithSign())
+	writeHashBytes(&buffer, tx.GasPrice.GetBytesWithSign())
+	writeHashBytes(&buffer, tx.Data)
+	buffer.Write(common.Uint64ToByte(tx.ValidFrom))
+	buffer.Write(common.Uint64ToByte(tx.ValidUntil))
+	// The signatures can't be authorized to other multisig accounts with the same owners
+	var multisigAccount *common.Address
+	if tx.MultiSign != nil {
+		multisigAccount = &tx.MultiSign.Account
+	}
+	writeHashAddress(&buffer, multisigAccount)
+	// The sender agrees on who pays, and the payer signs the same hash
+	var payer *common.Address
+	if tx.Sponsor != nil {
+		payer = &tx.Sponsor.Payer
+	}
+	writeHashAddress(&buffer, payer)
+
+	return common.BytesToHash(common.Sha256(buffer.Bytes()))
+}
+
+func (tx *Transaction) legacyHash() common.Hash {
 	buffer := bytes.Buffer{}
 	if tx.Data != nil {
 		buffer.Write(tx.Data)
@@ -80,12 +149,46 @@ func (tx *Transaction) GenHash() common.Hash {
 	return common.BytesToHash(common.Sha256(buffer.Bytes()))
 }
 
+// writeHashBytes writes the variable length field with its length, so that the fields can't be shifted into each other
+func writeHashBytes(buffer *bytes.Buffer, b []byte) {
+	buffer.Write(common.UInt32ToByte(uint32(len(b))))
+	buffer.Write(b)
+}
+
+// writeHashAddress writes the optional address field with a flag telling if it's set
+func writeHashAddress(buffer *bytes.Buffer, addr *common.Address) {
+	if addr == nil {
+		buffer.WriteByte(0)
+		return
+	}
+	buffer.WriteByte(1)
+	buffer.Write(addr.Bytes())
+}
+
+// checkChainID checks the fields not covered by the legacy hash are only used with the chain id
+func (tx *Transaction) checkChainID() error {
+	if tx.ChainID != 0 {
+		return nil
+	}
+	if tx.MultiSign != nil || tx.ValidFrom != 0 || tx.ValidUntil != 0 || tx.Sponsor != nil {
+		return ErrTxChainIDRequired
+	}
+	return nil
+}
+
 // RecoverSource recover source from the sign field.
 // It returns directly if source is not nil or it is a reward transaction.
+// Source of the multisig transaction can't be recovered without the account policy
 func (tx *Transaction) RecoverSource() error {
+	if err := tx.checkChainID(); err != nil {
+		return err
+	}
 	if tx.Source != nil {
 		return nil
 	}
+	if tx.MultiSign != nil {
+		return fmt.Errorf("multisig transaction should be verified against the account policy")
+	}
 	if tx.Sign == nil {
 		return fmt.Errorf("sign is nil")
 	}
@@ -97,8 +200,68 @@ func (tx *Transaction) RecoverSource() error {
 	return err
 }
 
+// VerifySponsor checks the sign of the fee payer on the transaction hash
+func (tx *Transaction) VerifySponsor() error {
+	if tx.Sponsor == nil {
+		return nil
+	}
+	if err := tx.checkChainID(); err != nil {
+		return err
+	}
+	if tx.Sponsor.Sign == nil {
+		return fmt.Errorf("fee payer sign is nil")
+	}
+	pk, err := tx.Sponsor.Sign.RecoverPubkey(tx.Hash.Bytes())
+	if err != nil {
+		return err
+	}
+	if pk.GetAddress() != tx.Sponsor.Payer {
+		return fmt.Errorf("fee payer sign not match %v", tx.Sponsor.Payer.Hex())
+	}
+	return nil
+}
+
+// GasPayer returns the account paying the gas of the transaction
+func (tx *Transaction) GasPayer() *common.Address {
+	if tx.Sponsor != nil {
+		return &tx.Sponsor.Payer
+	}
+	return tx.Source
+}
+
+// GasCost returns the max gas fee of the transaction
+func (tx *Transaction) GasCost() *big.Int {
+	return new(big.Int).Mul(tx.GasLimit.Value(), tx.GasPrice.Value())
+}
+
+// CheckValidHeight checks whether the transaction can be packed into the block of the given height
+func (tx *Transaction) CheckValidHeight(height uint64) error {
+	if tx.ValidUntil != 0 && tx.ValidFrom > tx.ValidUntil {
+		return ErrTxInvalidWindow
+	}
+	if height < tx.ValidFrom {
+		return ErrTxNotValidYet
+	}
+	if tx.ValidUntil != 0 && height > tx.ValidUntil {
+		return ErrTxWindowExpired
+	}
+	return nil
+}
+
+// ExpiredAt reports whether the transaction can't be packed into any block after the given height
+func (tx *Transaction) ExpiredAt(height uint64) bool {
+	return tx.ValidUntil != 0 && tx.ValidUntil <= height
+}
+
 func (tx *Transaction) Size() int {
-	return txFixSize + len(tx.Data)
+	size := txFixSize + len(tx.Data)
+	if tx.MultiSign != nil {
+		size += common.AddressLength + crypto.SignLength*len(tx.MultiSign.Signs)
+	}
+	if tx.Sponsor != nil {
+		size += common.AddressLength + crypto.SignLength
+	}
+	return size
 }
 
 func (tx Transaction) GetData() []byte { return tx.Data }
@@ -0,0 +1,147 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/vmihailenco/msgpack"
+
+	"github.com/xchain/go-chain/common"
+)
+
+func newWindowTx(validFrom, validUntil uint64) *Transaction {
+	target := common.BytesToAddress([]byte("receiver"))
+	tx := &Transaction{
+		Value:      NewBigInt(100),
+		Nonce:      1,
+		Target:     &target,
+		GasLimit:   NewBigInt(3000),
+		GasPrice:   NewBigInt(500),
+		ValidFrom:  validFrom,
+		ValidUntil: validUntil,
+		ChainID:    1,
+	}
+	tx.Hash = tx.GenHash()
+	return tx
+}
+
+func TestTransaction_ValidWindowHash(t *testing.T) {
+	tx := newWindowTx(0, 0)
+	if newWindowTx(0, 100).Hash == tx.Hash || newWindowTx(10, 0).Hash == tx.Hash || newWindowTx(10, 100).Hash == newWindowTx(100, 10).Hash {
+		t.Fatalf("validity window should be covered by the hash")
+	}
+
+	// Transactions without validity window are encoded the same as before
+	bs, err := msgpack.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := make(map[string]interface{})
+	if err := msgpack.Unmarshal(bs, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["vf"]; ok {
+		t.Fatalf("empty valid from should be omitted")
+	}
+	if _, ok := fields["vu"]; ok {
+		t.Fatalf("empty valid until should be omitted")
+	}
+
+	tx = newWindowTx(10, 100)
+	bs, _ = msgpack.Marshal(tx)
+	decoded := new(Transaction)
+	if err := msgpack.Unmarshal(bs, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ValidFrom != 10 || decoded.ValidUntil != 100 || decoded.GenHash() != tx.Hash {
+		t.Fatalf("validity window lost after decoding")
+	}
+}
+
+func TestTransaction_HashLayout(t *testing.T) {
+	target := common.BytesToAddress([]byte("receiver"))
+	legacy := &Transaction{
+		Data:     []byte("data"),
+		Value:    NewBigInt(100),
+		Nonce:    1,
+		Target:   &target,
+		GasLimit: NewBigInt(3000),
+		GasPrice: NewBigInt(500),
+	}
+	// The legacy transactions keep the hash signed before the chain id
+	buffer := append([]byte("data"), common.Uint64ToByte(1)...)
+	buffer = append(buffer, target.Bytes()...)
+	buffer = append(buffer, 0)
+	buffer = append(buffer, legacy.Value.GetBytesWithSign()...)
+	buffer = append(buffer, legacy.GasLimit.GetBytesWithSign()...)
+	buffer = append(buffer, legacy.GasPrice.GetBytesWithSign()...)
+	if legacy.GenHash() != common.BytesToHash(common.Sha256(buffer)) {
+		t.Fatalf("legacy hash changed")
+	}
+
+	// Moving the bytes between the fields changes the hash
+	tx := *legacy
+	tx.ChainID = 1
+	shifted := tx
+	shifted.Data = []byte("dat")
+	shifted.Target = nil
+	if tx.GenHash() == shifted.GenHash() {
+		t.Fatalf("fields should be delimited")
+	}
+	// The same address as multisig account or as payer is told apart
+	multisig, sponsored := tx, tx
+	multisig.MultiSign = &MultiSign{Account: target}
+	sponsored.Sponsor = &Sponsor{Payer: target}
+	if multisig.GenHash() == sponsored.GenHash() || multisig.GenHash() == tx.GenHash() {
+		t.Fatalf("multisig account and payer should be tagged")
+	}
+
+	// The fields not covered by the legacy hash require the chain id
+	legacy.ValidUntil = 10
+	if err := legacy.RecoverSource(); err != ErrTxChainIDRequired {
+		t.Fatalf("expect chain id required, got %v", err)
+	}
+	legacy.ValidUntil = 0
+	legacy.Sponsor = &Sponsor{Payer: target}
+	if err := legacy.VerifySponsor(); err != ErrTxChainIDRequired {
+		t.Fatalf("expect chain id required, got %v", err)
+	}
+}
+
+func TestTransaction_CheckValidHeight(t *testing.T) {
+	tx := newWindowTx(10, 100)
+	cases := map[uint64]error{
+		9:   ErrTxNotValidYet,
+		10:  nil,
+		100: nil,
+		101: ErrTxWindowExpired,
+	}
+	for height, expect := range cases {
+		if err := tx.CheckValidHeight(height); err != expect {
+			t.Fatalf("height %v: expect %v, got %v", height, expect, err)
+		}
+	}
+	if err := newWindowTx(0, 0).CheckValidHeight(1 << 40); err != nil {
+		t.Fatalf("transaction without window should always be valid")
+	}
+	if err := newWindowTx(100, 10).CheckValidHeight(50); err != ErrTxInvalidWindow {
+		t.Fatalf("invalid window should be rejected")
+	}
+	if !tx.ExpiredAt(100) || tx.ExpiredAt(99) {
+		t.Fatalf("unexpected expiry")
+	}
+}
@@ -0,0 +1,117 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"fmt"
+
+	"github.com/vmihailenco/msgpack"
+
+	"github.com/xchain/go-chain/common"
+)
+
+// HTLCPreimageLength is the required length of the preimage, same as the hash time-locked
+// contracts on bitcoin-like chains so that the swap can be done on both sides
+const HTLCPreimageLength = 32
+
+// HTLCState is the state of a hash time-locked transfer
+type HTLCState uint8
+
+const (
+	HTLCLocked   HTLCState = iota // Waiting to be claimed by the recipient or refunded after timeout
+	HTLCClaimed                   // Claimed by the recipient with the preimage
+	HTLCRefunded                  // Refunded to the sender after timeout
+)
+
+func (s HTLCState) String() string {
+	switch s {
+	case HTLCLocked:
+		return "locked"
+	case HTLCClaimed:
+		return "claimed"
+	case HTLCRefunded:
+		return "refunded"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(s))
+}
+
+// HTLCLockData is the data of a locking transaction, the recipient and the amount are
+// the target and the value of the transaction
+type HTLCLockData struct {
+	HashLock common.Hash `msgpack:"hl"` // Sha256 of the preimage
+	Timeout  uint64      `msgpack:"to"` // The last block height the lock can be claimed
+}
+
+// HTLC is a hash time-locked transfer, identified by its sender and hash lock
+type HTLC struct {
+	Sender    common.Address `msgpack:"sd"`
+	Recipient common.Address `msgpack:"rc"`
+	Amount    *BigInt        `msgpack:"am"`
+	HashLock  common.Hash    `msgpack:"hl"`
+	Timeout   uint64         `msgpack:"to"`
+	State     HTLCState      `msgpack:"st"`
+	Preimage  []byte         `msgpack:"pi,omitempty"` // Revealed by the claim, so the sender can claim on the other chain
+}
+
+// EncodeHTLCLock encodes the hash lock and timeout into the data of a locking transaction
+func EncodeHTLCLock(data *HTLCLockData) ([]byte, error) {
+	if data.HashLock == (common.Hash{}) {
+		return nil, fmt.Errorf("hash lock is empty")
+	}
+	return msgpack.Marshal(data)
+}
+
+// DecodeHTLCLock decodes the hash lock and timeout from the data of a locking transaction
+func DecodeHTLCLock(data []byte) (*HTLCLockData, error) {
+	lock := new(HTLCLockData)
+	if err := msgpack.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("decode htlc lock error:%v", err)
+	}
+	if lock.HashLock == (common.Hash{}) {
+		return nil, fmt.Errorf("hash lock is empty")
+	}
+	return lock, nil
+}
+
+// HTLCHashLock returns the hash lock of the preimage
+func HTLCHashLock(preimage []byte) common.Hash {
+	return common.BytesToHash(common.Sha256(preimage))
+}
+
+// EncodeHTLC encodes the lock to be stored in the account data
+func EncodeHTLC(h *HTLC) ([]byte, error) {
+	return msgpack.Marshal(h)
+}
+
+// DecodeHTLC decodes the lock stored in the account data
+func DecodeHTLC(data []byte) (*HTLC, error) {
+	h := new(HTLC)
+	if err := msgpack.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("decode htlc error:%v", err)
+	}
+	return h, nil
+}
+
+// Unlocks checks if the preimage opens the hash lock
+func (h *HTLC) Unlocks(preimage []byte) error {
+	if len(preimage) != HTLCPreimageLength {
+		return fmt.Errorf("preimage should be %v bytes, got %v", HTLCPreimageLength, len(preimage))
+	}
+	if HTLCHashLock(preimage) != h.HashLock {
+		return fmt.Errorf("preimage doesn't match the hash lock")
+	}
+	return nil
+}
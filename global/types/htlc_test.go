@@ -0,0 +1,58 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+)
+
+func TestHTLC_Encode(t *testing.T) {
+	preimage := common.Sha256([]byte("secret"))
+	lock := &HTLCLockData{HashLock: HTLCHashLock(preimage), Timeout: 100}
+	data, err := EncodeHTLCLock(lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeHTLCLock(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *decoded != *lock {
+		t.Fatalf("decoded lock not match")
+	}
+	if _, err := EncodeHTLCLock(&HTLCLockData{Timeout: 100}); err == nil {
+		t.Fatalf("empty hash lock should be rejected")
+	}
+	if _, err := DecodeHTLCLock([]byte("lock")); err == nil {
+		t.Fatalf("malformed data should be rejected")
+	}
+}
+
+func TestHTLC_Unlocks(t *testing.T) {
+	preimage := common.Sha256([]byte("secret"))
+	h := &HTLC{HashLock: HTLCHashLock(preimage)}
+	if err := h.Unlocks(preimage); err != nil {
+		t.Fatalf("unlock error:%v", err)
+	}
+	if err := h.Unlocks(common.Sha256([]byte("wrong"))); err == nil {
+		t.Fatalf("unlocked with wrong preimage")
+	}
+	if err := h.Unlocks([]byte("secret")); err == nil {
+		t.Fatalf("unlocked with short preimage")
+	}
+}
@@ -0,0 +1,204 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/xchain/go-chain/common"
+)
+
+// Prefixes of the hashed nodes, so that a leaf can't be taken as an inner node and vice versa
+const (
+	merkleLeafPrefix  = 0x00
+	merkleInnerPrefix = 0x01
+)
+
+// MerkleProof is the branch proving a leaf is included in the tree of the given number of leaves.
+// The last node of a level with odd nodes is promoted to the upper level as is, so it has no sibling
+type MerkleProof struct {
+	Index  uint32        `json:"index"`
+	Total  uint32        `json:"total"`
+	Branch []common.Hash `json:"branch"`
+}
+
+func merkleLeaf(data []byte) common.Hash {
+	return common.BytesToHash(common.Sha256(append([]byte{merkleLeafPrefix}, data...)))
+}
+
+func merkleInner(left, right common.Hash) common.Hash {
+	buf := bytes.NewBuffer([]byte{merkleInnerPrefix})
+	buf.Write(left.Bytes())
+	buf.Write(right.Bytes())
+	return common.BytesToHash(common.Sha256(buf.Bytes()))
+}
+
+// merkleLevels returns all levels of the tree, from the leaves to the root
+func merkleLevels(leaves []common.Hash) [][]common.Hash {
+	levels := [][]common.Hash{leaves}
+	for level := leaves; len(level) > 1; {
+		upper := make([]common.Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				upper = append(upper, level[i])
+			} else {
+				upper = append(upper, merkleInner(level[i], level[i+1]))
+			}
+		}
+		levels = append(levels, upper)
+		level = upper
+	}
+	return levels
+}
+
+// MerkleRoot returns the root of the leaves, or the empty hash if there are no leaves
+func MerkleRoot(leaves []common.Hash) common.Hash {
+	if len(leaves) == 0 {
+		return common.EmptyHash
+	}
+	levels := merkleLevels(leaves)
+	return levels[len(levels)-1][0]
+}
+
+// NewMerkleProof returns the proof of the leaf at the index
+func NewMerkleProof(leaves []common.Hash, index int) (*MerkleProof, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("index %v out of range [0, %v)", index, len(leaves))
+	}
+	proof := &MerkleProof{Index: uint32(index), Total: uint32(len(leaves)), Branch: make([]common.Hash, 0)}
+	levels := merkleLevels(leaves)
+	for _, level := range levels[:len(levels)-1] {
+		if sibling := index ^ 1; sibling < len(level) {
+			proof.Branch = append(proof.Branch, level[sibling])
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof checks the leaf is included in the tree of the root at the index of the proof
+func VerifyMerkleProof(root common.Hash, leaf common.Hash, proof *MerkleProof) error {
+	if proof == nil || proof.Index >= proof.Total {
+		return fmt.Errorf("invalid proof")
+	}
+	node, index, n := leaf, proof.Index, proof.Total
+	branch := proof.Branch
+	for ; n > 1; n = (n + 1) / 2 {
+		// The last node of an odd level has no sibling
+		if index != n-1 || n%2 == 0 {
+			if len(branch) == 0 {
+				return fmt.Errorf("proof branch too short")
+			}
+			if index%2 == 0 {
+				node = merkleInner(node, branch[0])
+			} else {
+				node = merkleInner(branch[0], node)
+			}
+			branch = branch[1:]
+		}
+		index /= 2
+	}
+	if len(branch) != 0 {
+		return fmt.Errorf("proof branch too long")
+	}
+	if node != root {
+		return fmt.Errorf("root mismatch, expect %v, got %v", root.Hex(), node.Hex())
+	}
+	return nil
+}
+
+// TxLeaf returns the leaf of the transaction in the transaction tree
+func TxLeaf(txHash common.Hash) common.Hash {
+	return merkleLeaf(txHash.Bytes())
+}
+
+// ReceiptLeaf returns the leaf of the receipt in the receipt tree. Only the fields decided by the
+// execution are hashed, the height and index are bound by the header and the proof
+func ReceiptLeaf(r *Receipt) common.Hash {
+	buf := bytes.NewBuffer(r.TxHash.Bytes())
+	buf.Write(common.UInt64ToByte(uint64(r.Status)))
+	buf.Write(common.UInt64ToByte(r.CumulativeGasUsed))
+	for _, s := range r.TransferStatus {
+		buf.Write(common.UInt64ToByte(uint64(s)))
+	}
+	return merkleLeaf(buf.Bytes())
+}
+
+func txLeaves(txs []*Transaction) []common.Hash {
+	leaves := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		leaves[i] = TxLeaf(tx.Hash)
+	}
+	return leaves
+}
+
+func receiptLeaves(receipts Receipts) []common.Hash {
+	leaves := make([]common.Hash, len(receipts))
+	for i, r := range receipts {
+		leaves[i] = ReceiptLeaf(r)
+	}
+	return leaves
+}
+
+// CalcTxTree returns the root of the transactions, set to the TxTree of the block header
+func CalcTxTree(txs []*Transaction) common.Hash {
+	return MerkleRoot(txLeaves(txs))
+}
+
+// CalcReceiptTree returns the root of the receipts, set to the ReceiptTree of the block header
+func CalcReceiptTree(receipts Receipts) common.Hash {
+	return MerkleRoot(receiptLeaves(receipts))
+}
+
+// NewTxProof returns the proof of the transaction at the index in the transaction tree
+func NewTxProof(txs []*Transaction, index int) (*MerkleProof, error) {
+	return NewMerkleProof(txLeaves(txs), index)
+}
+
+// NewReceiptProof returns the proof of the receipt at the index in the receipt tree
+func NewReceiptProof(receipts Receipts, index int) (*MerkleProof, error) {
+	return NewMerkleProof(receiptLeaves(receipts), index)
+}
+
+// verifyHeader checks the hash of the header, which is trusted by the verifier on its own
+func verifyHeader(header *BlockHeader) error {
+	if header == nil {
+		return fmt.Errorf("header is nil")
+	}
+	if header.GenHash() != header.Hash {
+		return fmt.Errorf("header hash mismatch")
+	}
+	return nil
+}
+
+// VerifyTxProof checks the transaction is included in the block of the header.
+// The verifier should have checked the header hash is on the canonical chain
+func VerifyTxProof(header *BlockHeader, txHash common.Hash, proof *MerkleProof) error {
+	if err := verifyHeader(header); err != nil {
+		return err
+	}
+	return VerifyMerkleProof(header.TxTree, TxLeaf(txHash), proof)
+}
+
+// VerifyReceiptProof checks the receipt is included in the block of the header.
+// The verifier should have checked the header hash is on the canonical chain
+func VerifyReceiptProof(header *BlockHeader, receipt *Receipt, proof *MerkleProof) error {
+	if err := verifyHeader(header); err != nil {
+		return err
+	}
+	return VerifyMerkleProof(header.ReceiptTree, ReceiptLeaf(receipt), proof)
+}
@@ -0,0 +1,126 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+)
+
+func newMerkleLeaves(n int) []common.Hash {
+	leaves := make([]common.Hash, n)
+	for i := range leaves {
+		leaves[i] = merkleLeaf(common.UInt64ToByte(uint64(i)))
+	}
+	return leaves
+}
+
+func TestMerkleProof_AllSizes(t *testing.T) {
+	if MerkleRoot(nil) != common.EmptyHash {
+		t.Fatalf("root of no leaves should be empty")
+	}
+	for n := 1; n <= 17; n++ {
+		leaves := newMerkleLeaves(n)
+		root := MerkleRoot(leaves)
+		for i := 0; i < n; i++ {
+			proof, err := NewMerkleProof(leaves, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := VerifyMerkleProof(root, leaves[i], proof); err != nil {
+				t.Fatalf("verify leaf %v of %v error:%v", i, n, err)
+			}
+			if n > 1 {
+				// The proof is bound to the position of the leaf
+				moved := *proof
+				moved.Index = uint32((i + 1) % n)
+				if VerifyMerkleProof(root, leaves[i], &moved) == nil {
+					t.Fatalf("leaf %v of %v verified at wrong index", i, n)
+				}
+				if VerifyMerkleProof(root, leaves[(i+1)%n], proof) == nil {
+					t.Fatalf("wrong leaf verified at %v of %v", i, n)
+				}
+			}
+		}
+		if _, err := NewMerkleProof(leaves, n); err == nil {
+			t.Fatalf("index out of range should fail")
+		}
+	}
+}
+
+func TestMerkleProof_Tampered(t *testing.T) {
+	leaves := newMerkleLeaves(5)
+	root := MerkleRoot(leaves)
+	proof, _ := NewMerkleProof(leaves, 2)
+
+	tampered := &MerkleProof{Index: proof.Index, Total: proof.Total, Branch: append([]common.Hash{}, proof.Branch...)}
+	tampered.Branch[0][0] ^= 1
+	if VerifyMerkleProof(root, leaves[2], tampered) == nil {
+		t.Fatalf("tampered branch verified")
+	}
+	if VerifyMerkleProof(root, leaves[2], &MerkleProof{Index: 2, Total: 5, Branch: proof.Branch[1:]}) == nil {
+		t.Fatalf("short branch verified")
+	}
+	if VerifyMerkleProof(root, leaves[2], &MerkleProof{Index: 2, Total: 5, Branch: append(proof.Branch, root)}) == nil {
+		t.Fatalf("long branch verified")
+	}
+	// An inner node can't be proved as a leaf of a smaller tree
+	leaves = newMerkleLeaves(4)
+	left, right := merkleInner(leaves[0], leaves[1]), merkleInner(leaves[2], leaves[3])
+	if VerifyMerkleProof(MerkleRoot(leaves), merkleLeaf(left.Bytes()), &MerkleProof{Index: 0, Total: 2, Branch: []common.Hash{right}}) == nil {
+		t.Fatalf("inner node verified as leaf")
+	}
+}
+
+func TestVerifyTxAndReceiptProof(t *testing.T) {
+	txs := []*Transaction{newWindowTx(0, 0), newWindowTx(0, 100), newWindowTx(10, 0)}
+	receipts := make(Receipts, len(txs))
+	for i, tx := range txs {
+		receipts[i] = &Receipt{TxHash: tx.Hash, Status: RSSuccess, CumulativeGasUsed: uint64(i+1) * 1000}
+	}
+	receipts[1].Status = RSFail
+
+	header := &BlockHeader{
+		Height:               10,
+		TxTree:               CalcTxTree(txs),
+		ReceiptTree:          CalcReceiptTree(receipts),
+		CumulativeDifficulty: big.NewInt(1),
+	}
+	header.Hash = header.GenHash()
+
+	txProof, _ := NewTxProof(txs, 1)
+	if err := VerifyTxProof(header, txs[1].Hash, txProof); err != nil {
+		t.Fatalf("verify tx error:%v", err)
+	}
+	receiptProof, _ := NewReceiptProof(receipts, 1)
+	if err := VerifyReceiptProof(header, receipts[1], receiptProof); err != nil {
+		t.Fatalf("verify receipt error:%v", err)
+	}
+
+	// The receipt can't be claimed as succeeded
+	forged := *receipts[1]
+	forged.Status = RSSuccess
+	if VerifyReceiptProof(header, &forged, receiptProof) == nil {
+		t.Fatalf("forged receipt verified")
+	}
+	// The header must match its hash
+	header.TxTree = CalcTxTree(txs[:2])
+	if VerifyTxProof(header, txs[1].Hash, txProof) == nil {
+		t.Fatalf("forged header verified")
+	}
+}
@@ -28,6 +28,68 @@ func (m *BlockOnChainSuccMessage) GetData() interface{} {
 	return m.Block
 }
 
+// TxReplacedMessage announces that a pool transaction is replaced by another one
+// from the same source with the same nonce but higher gas price
+type TxReplacedMessage struct {
+	Old *Transaction
+	New *Transaction
+}
+
+func (m *TxReplacedMessage) GetRaw() []byte {
+	return []byte{}
+}
+func (m *TxReplacedMessage) GetData() interface{} {
+	return m
+}
+
+// TxExpiredMessage announces the transactions removed from the pool for staying too long.
+// Pending ones are usually underpriced, and queued ones are usually waiting for a missing nonce
+type TxExpiredMessage struct {
+	Pending []*Transaction
+	Queued  []*Transaction
+}
+
+func (m *TxExpiredMessage) GetRaw() []byte {
+	return []byte{}
+}
+func (m *TxExpiredMessage) GetData() interface{} {
+	return m
+}
+
+// TxAddedMessage announces that a new transaction is accepted by the pool
+type TxAddedMessage struct {
+	Tx *Transaction
+}
+
+func (m *TxAddedMessage) GetRaw() []byte {
+	return []byte{}
+}
+func (m *TxAddedMessage) GetData() interface{} {
+	return m.Tx
+}
+
+// TxBroadcastMessage asks the network to broadcast the transactions, e.g. the local ones of the pool
+// which are not included yet. The raw is the marshaled transactions
+type TxBroadcastMessage struct {
+	Txs []*Transaction
+	raw []byte
+}
+
+func NewTxBroadcastMessage(txs []*Transaction) (*TxBroadcastMessage, error) {
+	raw, err := MarshalTransactions(txs)
+	if err != nil {
+		return nil, err
+	}
+	return &TxBroadcastMessage{Txs: txs, raw: raw}, nil
+}
+
+func (m *TxBroadcastMessage) GetRaw() []byte {
+	return m.raw
+}
+func (m *TxBroadcastMessage) GetData() interface{} {
+	return m.Txs
+}
+
 // DefaultMessage is a default implementation of the Message interface.
 // It can meet most of demands abort core event
 type DefaultMessage struct {
@@ -0,0 +1,158 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"fmt"
+
+	"github.com/vmihailenco/msgpack"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/crypto"
+)
+
+// MaxMultisigOwners is the max number of public keys of a multisig account
+const MaxMultisigOwners = 20
+
+// MultisigPolicy defines the public keys of a multisig account and the number of signatures
+// required to authorize a transaction from it
+type MultisigPolicy struct {
+	Threshold uint8    `msgpack:"th"`
+	PubKeys   [][]byte `msgpack:"pk"`
+}
+
+// MultiSign carries the signatures of a transaction sent from a multisig account
+type MultiSign struct {
+	Account common.Address `msgpack:"acc"` // The multisig account which sends the transaction
+	Signs   []*crypto.Sign `msgpack:"sis"` // Signatures of the owners
+}
+
+// EncodeMultisigPolicy encodes the policy into the data of a multisig creating transaction
+func EncodeMultisigPolicy(policy *MultisigPolicy) ([]byte, error) {
+	if err := policy.check(); err != nil {
+		return nil, err
+	}
+	return msgpack.Marshal(policy)
+}
+
+// DecodeMultisigPolicy decodes the policy from the data of a multisig creating transaction
+// or the data stored in the multisig account
+func DecodeMultisigPolicy(data []byte) (*MultisigPolicy, error) {
+	policy := new(MultisigPolicy)
+	if err := msgpack.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("decode multisig policy error:%v", err)
+	}
+	if err := policy.check(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (p *MultisigPolicy) check() error {
+	n := len(p.PubKeys)
+	if n == 0 || n > MaxMultisigOwners {
+		return fmt.Errorf("multisig owners should be in [1, %v], got %v", MaxMultisigOwners, n)
+	}
+	if p.Threshold == 0 || int(p.Threshold) > n {
+		return fmt.Errorf("multisig threshold should be in [1, %v], got %v", n, p.Threshold)
+	}
+	owners := make(map[common.Address]struct{}, n)
+	for i, bs := range p.PubKeys {
+		pk, err := parsePubKey(bs)
+		if err != nil {
+			return fmt.Errorf("public key %v: %v", i, err)
+		}
+		if _, ok := owners[pk.GetAddress()]; ok {
+			return fmt.Errorf("duplicate public key %v", i)
+		}
+		owners[pk.GetAddress()] = struct{}{}
+	}
+	return nil
+}
+
+// Owners returns the addresses of the public keys
+func (p *MultisigPolicy) Owners() []common.Address {
+	owners := make([]common.Address, 0, len(p.PubKeys))
+	for _, bs := range p.PubKeys {
+		if pk, err := parsePubKey(bs); err == nil {
+			owners = append(owners, pk.GetAddress())
+		}
+	}
+	return owners
+}
+
+// Authorized checks if the signers satisfy the policy. Each owner counts once no matter how many times it signs
+func (p *MultisigPolicy) Authorized(signers []common.Address) error {
+	owners := make(map[common.Address]bool)
+	for _, owner := range p.Owners() {
+		owners[owner] = false
+	}
+	signed := 0
+	for _, signer := range signers {
+		counted, ok := owners[signer]
+		if !ok {
+			return fmt.Errorf("%v is not an owner of the multisig account", signer.Hex())
+		}
+		if !counted {
+			owners[signer] = true
+			signed++
+		}
+	}
+	if signed < int(p.Threshold) {
+		return fmt.Errorf("not enough signatures, %v of %v", signed, p.Threshold)
+	}
+	return nil
+}
+
+// MultisigAddress returns the address of the multisig account created by the given transaction.
+// It's derived from the transaction hash, so it can't be known before the transaction is signed
+func MultisigAddress(creator common.Address, txHash common.Hash) common.Address {
+	buf := append([]byte("multisig"), creator.Bytes()...)
+	buf = append(buf, txHash.Bytes()...)
+	return common.BytesToAddress(common.Sha256(buf))
+}
+
+// RecoverSigners recovers the addresses of the signers of a multisig transaction
+func (tx *Transaction) RecoverSigners() ([]common.Address, error) {
+	if tx.MultiSign == nil || len(tx.MultiSign.Signs) == 0 {
+		return nil, fmt.Errorf("multi sign is empty")
+	}
+	if err := tx.checkChainID(); err != nil {
+		return nil, err
+	}
+	signers := make([]common.Address, 0, len(tx.MultiSign.Signs))
+	for _, sign := range tx.MultiSign.Signs {
+		if sign == nil {
+			return nil, fmt.Errorf("sign is nil")
+		}
+		pk, err := sign.RecoverPubkey(tx.Hash.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, pk.GetAddress())
+	}
+	return signers, nil
+}
+
+// parsePubKey parses the public key without panic on the malformed data
+func parsePubKey(data []byte) (pk *crypto.PublicKey, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid public key")
+		}
+	}()
+	return crypto.BytesToPublicKey(data), nil
+}
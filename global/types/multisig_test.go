@@ -0,0 +1,93 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/vmihailenco/msgpack"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/crypto"
+)
+
+func TestMultisigPolicy_Check(t *testing.T) {
+	sk1, _ := crypto.GenerateKey("")
+	sk2, _ := crypto.GenerateKey("")
+	pk1, pk2 := sk1.GetPubKey().Bytes(), sk2.GetPubKey().Bytes()
+
+	invalid := map[string]*MultisigPolicy{
+		"no owner":          {Threshold: 1},
+		"zero threshold":    {Threshold: 0, PubKeys: [][]byte{pk1}},
+		"threshold too big": {Threshold: 3, PubKeys: [][]byte{pk1, pk2}},
+		"duplicate key":     {Threshold: 1, PubKeys: [][]byte{pk1, pk1}},
+		"malformed key":     {Threshold: 1, PubKeys: [][]byte{[]byte("key")}},
+	}
+	for name, policy := range invalid {
+		if _, err := EncodeMultisigPolicy(policy); err == nil {
+			t.Fatalf("%v: should be invalid", name)
+		}
+	}
+
+	data, err := EncodeMultisigPolicy(&MultisigPolicy{Threshold: 2, PubKeys: [][]byte{pk1, pk2}})
+	if err != nil {
+		t.Fatalf("encode error:%v", err)
+	}
+	policy, err := DecodeMultisigPolicy(data)
+	if err != nil {
+		t.Fatalf("decode error:%v", err)
+	}
+	owners := policy.Owners()
+	if len(owners) != 2 || owners[0] != sk1.GetPubKey().GetAddress() {
+		t.Fatalf("unexpected owners")
+	}
+}
+
+func TestMultiSign_Serialize(t *testing.T) {
+	sk, _ := crypto.GenerateKey("")
+	target := common.BytesToAddress([]byte("receiver"))
+	tx := &Transaction{
+		Value:     NewBigInt(100),
+		Nonce:     1,
+		Target:    &target,
+		GasLimit:  NewBigInt(3000),
+		GasPrice:  NewBigInt(500),
+		MultiSign: &MultiSign{Account: common.BytesToAddress([]byte("multisig"))},
+		ChainID:   1,
+	}
+	tx.Hash = tx.GenHash()
+	sign, _ := sk.Sign(tx.Hash.Bytes())
+	tx.MultiSign.Signs = append(tx.MultiSign.Signs, &sign)
+
+	bs, err := msgpack.Marshal(tx)
+	if err != nil {
+		t.Fatalf("marshal error:%v", err)
+	}
+	decoded := new(Transaction)
+	if err := msgpack.Unmarshal(bs, decoded); err != nil {
+		t.Fatalf("unmarshal error:%v", err)
+	}
+	if decoded.GenHash() != tx.Hash {
+		t.Fatalf("hash changed after decoding")
+	}
+	if err := decoded.RecoverSource(); err == nil {
+		t.Fatalf("multisig source shouldn't be recovered without policy")
+	}
+	signers, err := decoded.RecoverSigners()
+	if err != nil || len(signers) != 1 || signers[0] != sk.GetPubKey().GetAddress() {
+		t.Fatalf("unexpected signers %v %v", signers, err)
+	}
+}
@@ -16,11 +16,14 @@
 package types
 
 import (
+	"fmt"
 	"github.com/gogo/protobuf/proto"
+	"github.com/vmihailenco/msgpack"
 	"github.com/xchain/go-chain/common"
 	"github.com/xchain/go-chain/crypto"
 	"github.com/xchain/go-chain/middleware/pb"
 	time2 "github.com/xchain/go-chain/middleware/time"
+	"math"
 	"math/big"
 )
 
@@ -32,8 +35,7 @@ func UnMarshalTransactions(b []byte) ([]*Transaction, error) {
 		return nil, error
 	}
 
-	result := PbToTransactions(ts.Transactions)
-	return result, nil
+	return pbToTransactions(ts.Transactions)
 }
 
 // UnMarshalBlock deserialize from []byte to *Block
@@ -43,8 +45,7 @@ func UnMarshalBlock(bytes []byte) (*Block, error) {
 	if error != nil {
 		return nil, error
 	}
-	block := PbToBlock(b)
-	return block, nil
+	return pbToBlock(b)
 }
 
 // UnMarshalBlockHeader deserialize from []byte to *BlockHeader
@@ -109,9 +110,9 @@ func byteToHash(b []byte) common.Hash {
 	return common.BytesToHash(b)
 }
 
-func pbToTransaction(t *pb.Transaction) *Transaction {
+func pbToTransaction(t *pb.Transaction) (*Transaction, error) {
 	if t == nil {
-		return &Transaction{}
+		return &Transaction{}, nil
 	}
 
 	var target *common.Address
@@ -124,31 +125,71 @@ func pbToTransaction(t *pb.Transaction) *Transaction {
 	gasPrice := NewBigInt(0).SetBytesWithSign(t.GasPrice)
 
 	transaction := &Transaction{
-		Data:     t.Data,
-		Value:    value,
-		Nonce:    ensureUint64(t.Nonce),
-		Target:   target,
-		GasLimit: gasLimit,
-		GasPrice: gasPrice,
-		Hash:     byteToHash(t.Hash),
-		Type:     int8(ensureInt32(t.Type)),
-		Sign:     crypto.BytesToSign(t.Sign),
+		Data:       t.Data,
+		Value:      value,
+		Nonce:      ensureUint64(t.Nonce),
+		Target:     target,
+		GasLimit:   gasLimit,
+		GasPrice:   gasPrice,
+		Hash:       byteToHash(t.Hash),
+		Type:       int8(ensureInt32(t.Type)),
+		Sign:       crypto.BytesToSign(t.Sign),
+		ValidFrom:  t.GetValidFrom(),
+		ValidUntil: t.GetValidUntil(),
+	}
+	if t.ChainID != nil {
+		if *t.ChainID > math.MaxUint16 {
+			return nil, fmt.Errorf("chain id %v out of range", *t.ChainID)
+		}
+		transaction.ChainID = uint16(*t.ChainID)
+	}
+	if len(t.Sponsor) > 0 {
+		transaction.Sponsor = new(Sponsor)
+		if err := msgpack.Unmarshal(t.Sponsor, transaction.Sponsor); err != nil {
+			return nil, fmt.Errorf("decode sponsor of %x error:%v", t.Hash, err)
+		}
+	}
+	if len(t.MultiSign) > 0 {
+		transaction.MultiSign = new(MultiSign)
+		if err := msgpack.Unmarshal(t.MultiSign, transaction.MultiSign); err != nil {
+			return nil, fmt.Errorf("decode multi sign of %x error:%v", t.Hash, err)
+		}
+		if len(t.Sign) == 0 {
+			transaction.Sign = nil
+		}
 	}
-	return transaction
+	return transaction, nil
 }
 
+// PbToTransactions converts the pb transactions, the ones with the extension fields failed to decode are skipped
 func PbToTransactions(txs []*pb.Transaction) []*Transaction {
 	result := make([]*Transaction, 0)
 	if txs == nil {
 		return result
 	}
 	for _, t := range txs {
-		transaction := pbToTransaction(t)
+		transaction, err := pbToTransaction(t)
+		if err != nil {
+			continue
+		}
 		result = append(result, transaction)
 	}
 	return result
 }
 
+// pbToTransactions converts the pb transactions, fails if any of them can't be decoded
+func pbToTransactions(txs []*pb.Transaction) ([]*Transaction, error) {
+	result := make([]*Transaction, 0, len(txs))
+	for _, t := range txs {
+		transaction, err := pbToTransaction(t)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, transaction)
+	}
+	return result, nil
+}
+
 func PbToBlockHeader(h *pb.BlockHeader) *BlockHeader {
 	if h == nil {
 		return nil
@@ -176,6 +217,8 @@ func PbToBlockHeader(h *pb.BlockHeader) *BlockHeader {
 	return &header
 }
 
+// PbToBlock converts the pb block, the transactions failed to decode are skipped,
+// so the block won't match its transaction tree
 func PbToBlock(b *pb.Block) *Block {
 	if b == nil {
 		return nil
@@ -186,16 +229,34 @@ func PbToBlock(b *pb.Block) *Block {
 	return &block
 }
 
+// pbToBlock converts the pb block, fails if any of the transactions can't be decoded
+func pbToBlock(b *pb.Block) (*Block, error) {
+	if b == nil {
+		return nil, nil
+	}
+	txs, err := pbToTransactions(b.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	block := Block{Header: PbToBlockHeader(b.Header), Transactions: txs}
+	return &block, nil
+}
+
 func transactionToPb(t *Transaction) *pb.Transaction {
 	if t == nil {
 		return nil
 	}
 	var (
 		target []byte
+		sign   []byte
 	)
 	if t.Target != nil {
 		target = t.Target.Bytes()
 	}
+	// Transactions from the multisig accounts have no single sign
+	if t.Sign != nil {
+		sign = t.Sign.Bytes()
+	}
 
 	tp := int32(t.Type)
 	transaction := pb.Transaction{
@@ -207,7 +268,24 @@ func transactionToPb(t *Transaction) *pb.Transaction {
 		GasPrice: t.GasPrice.GetBytesWithSign(),
 		Hash:     t.Hash.Bytes(),
 		Type:     &tp,
-		Sign:     t.Sign.Bytes(),
+		Sign:     sign,
+	}
+	if t.MultiSign != nil {
+		transaction.MultiSign, _ = msgpack.Marshal(t.MultiSign)
+	}
+	if t.Sponsor != nil {
+		transaction.Sponsor, _ = msgpack.Marshal(t.Sponsor)
+	}
+	// Optional fields are left unset if not used, so that the old nodes decode the same transaction
+	if t.ValidFrom != 0 {
+		transaction.ValidFrom = &t.ValidFrom
+	}
+	if t.ValidUntil != 0 {
+		transaction.ValidUntil = &t.ValidUntil
+	}
+	if t.ChainID != 0 {
+		chainID := uint32(t.ChainID)
+		transaction.ChainID = &chainID
 	}
 	return &transaction
 }
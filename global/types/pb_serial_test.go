@@ -0,0 +1,76 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/middleware/pb"
+)
+
+func TestMarshalTransactions_ExtensionFields(t *testing.T) {
+	tx := newWindowTx(10, 20)
+	tx.ChainID = 7
+	tx.Sponsor = &Sponsor{Payer: common.BytesToAddress([]byte("payer"))}
+	tx.MultiSign = &MultiSign{Account: common.BytesToAddress([]byte("multisig"))}
+	tx.Hash = tx.GenHash()
+
+	b, err := MarshalTransactions([]*Transaction{tx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs, err := UnMarshalTransactions(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 {
+		t.Fatalf("expect 1 tx, got %v", len(txs))
+	}
+	got := txs[0]
+	if got.ValidFrom != 10 || got.ValidUntil != 20 || got.ChainID != 7 {
+		t.Errorf("window or chain id lost: %v %v %v", got.ValidFrom, got.ValidUntil, got.ChainID)
+	}
+	if got.Sponsor == nil || got.Sponsor.Payer != tx.Sponsor.Payer {
+		t.Errorf("sponsor lost")
+	}
+	if got.MultiSign == nil || got.MultiSign.Account != tx.MultiSign.Account {
+		t.Errorf("multi sign lost")
+	}
+	if got.GenHash() != tx.Hash {
+		t.Errorf("hash changed after decoding")
+	}
+}
+
+func TestUnMarshalTransactions_DecodeError(t *testing.T) {
+	tooLarge := uint32(1 << 16)
+	slices := []*pb.TransactionSlice{
+		{Transactions: []*pb.Transaction{{Sponsor: []byte{0xc1}}}},
+		{Transactions: []*pb.Transaction{{MultiSign: []byte{0xc1}}}},
+		{Transactions: []*pb.Transaction{{ChainID: &tooLarge}}},
+	}
+	for i, slice := range slices {
+		b, err := proto.Marshal(slice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := UnMarshalTransactions(b); err == nil {
+			t.Errorf("case %v: expect decode error", i)
+		}
+	}
+}
@@ -40,6 +40,8 @@ type Receipt struct {
 	TxHash  common.Hash `json:"transactionHash" gencodec:"required"`
 	Height  uint64      `json:"height"`
 	TxIndex uint16      `json:"tx_index"`
+
+	TransferStatus []ReceiptStatus `json:"transferStatus,omitempty"` // Status of each transfer of a batch transfer transaction
 }
 
 func NewReceipt(root []byte, status ReceiptStatus, cumulativeGasUsed uint64) *Receipt {
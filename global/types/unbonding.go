@@ -0,0 +1,60 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+// Unbonding is the stake reduced or undelegated which can be claimed after the maturity height
+type Unbonding struct {
+	Amount   *BigInt `msgpack:"am"`
+	Maturity uint64  `msgpack:"mt"`
+}
+
+// UnbondingQueue is the withdrawal queue of an account, ordered by the maturity height
+type UnbondingQueue []*Unbonding
+
+// EncodeUnbondingQueue encodes the queue to be stored in the account data
+func EncodeUnbondingQueue(q UnbondingQueue) ([]byte, error) {
+	return msgpack.Marshal(q)
+}
+
+// DecodeUnbondingQueue decodes the queue stored in the account data
+func DecodeUnbondingQueue(data []byte) (UnbondingQueue, error) {
+	q := make(UnbondingQueue, 0)
+	if err := msgpack.Unmarshal(data, &q); err != nil {
+		return nil, fmt.Errorf("decode unbonding queue error:%v", err)
+	}
+	return q, nil
+}
+
+// Matured splits the queue into the total amount matured at the height and the ones still pending
+func (q UnbondingQueue) Matured(height uint64) (*big.Int, UnbondingQueue) {
+	total := new(big.Int)
+	pending := make(UnbondingQueue, 0, len(q))
+	for _, u := range q {
+		if u.Maturity <= height {
+			total.Add(total, u.Amount.Value())
+		} else {
+			pending = append(pending, u)
+		}
+	}
+	return total, pending
+}
@@ -0,0 +1,43 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"testing"
+)
+
+func TestUnbondingQueue_Matured(t *testing.T) {
+	q := UnbondingQueue{
+		{Amount: NewBigInt(100), Maturity: 10},
+		{Amount: NewBigInt(200), Maturity: 20},
+		{Amount: NewBigInt(300), Maturity: 30},
+	}
+	data, err := EncodeUnbondingQueue(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeUnbondingQueue(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matured, pending := decoded.Matured(20)
+	if matured.Uint64() != 300 {
+		t.Fatalf("unexpected matured amount %v", matured)
+	}
+	if len(pending) != 1 || pending[0].Maturity != 30 || pending[0].Amount.Uint64() != 300 {
+		t.Fatalf("unexpected pending unbondings")
+	}
+}
@@ -37,5 +37,12 @@ const (
 	TxSyncReq      = "tx_sync_req"
 	TxSyncResponse = "tx_sync_response"
 
+	TxAdded    = "tx_added"
+	TxReplaced = "tx_replaced"
+	TxExpired  = "tx_expired"
+
+	TxBroadcast = "tx_broadcast"
+	TxAnnounce  = "tx_announce"
+
 	ConsoleMessage = "console_msg"
 )
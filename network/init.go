@@ -19,6 +19,8 @@
 package network
 
 import (
+	"github.com/xchain/go-chain/global"
+	"github.com/xchain/go-chain/middleware/notify"
 	"github.com/xchain/go-chain/xlog"
 	"math"
 	"math/rand"
@@ -121,6 +123,7 @@ func Init(networkConfig NetworkConfig) (err error) {
 	n, _ := netCore.InitNetCore(netConfig)
 
 	netServerInstance = &Server{Self: self, netCore: n, config: &networkConfig}
+	global.Context().Bus.Subscribe(notify.TxBroadcast, netServerInstance.onTxBroadcast)
 	return nil
 }
 
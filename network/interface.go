@@ -31,6 +31,9 @@ const (
 	TxSyncNotify   uint32 = 7
 	TxSyncReq      uint32 = 8
 	TxSyncResponse uint32 = 9
+
+	//The following message is used for announcing the transactions to the peers without request
+	TxAnnounceMsg uint32 = 10
 )
 
 type Message struct {
@@ -125,6 +125,8 @@ func (s *Server) handleMessageInner(message *Message, from string) {
 		topicID = notify.TxSyncReq
 	case TxSyncResponse:
 		topicID = notify.TxSyncResponse
+	case TxAnnounceMsg:
+		topicID = notify.TxAnnounce
 	case BlockInfoNotifyMsg:
 		topicID = notify.BlockInfoNotify
 	case ReqBlock:
@@ -148,6 +150,14 @@ func (s *Server) handleMessageInner(message *Message, from string) {
 	}
 }
 
+// onTxBroadcast announces the transactions the pool asks to broadcast to all the nodes, so that the receivers
+// add them into their pools
+func (s *Server) onTxBroadcast(message notify.Message) {
+	if err := s.Broadcast(Message{Code: TxAnnounceMsg, Body: message.GetRaw()}); err != nil {
+		Logger.Errorf("broadcast txs error:%v", err)
+	}
+}
+
 func marshalMessage(m Message) ([]byte, error) {
 	message := pb.Message{Code: &m.Code, Body: m.Body}
 	return proto.Marshal(&message)
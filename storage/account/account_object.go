@@ -81,9 +81,10 @@ type accountObject struct {
 	trie Trie // storage trie, which becomes non-nil on first access
 	code Code // contract code, which gets set when code is loaded
 
-	cachedLock    sync.RWMutex
-	cachedStorage Storage // Storage cache of original entries to dedup rewrites
-	dirtyStorage  Storage // Storage entries that need to be flushed to disk
+	cachedLock     sync.RWMutex
+	cachedStorage  Storage // Storage cache of original entries to dedup rewrites
+	dirtyStorage   Storage // Storage entries that need to be flushed to disk
+	pendingStorage Storage // Storage entries written since the last commit, for the flat state
 
 	dirtyCode bool // true if the code was updated
 	suicided  bool
@@ -115,13 +116,14 @@ func newAccountObject(db *AccountDB, address common.Address, data Account, onDir
 		data.CodeHash = emptyCodeHash[:]
 	}
 	return &accountObject{
-		db:            db,
-		address:       address,
-		addrHash:      sha3.Sum256(address[:]),
-		data:          data,
-		cachedStorage: make(Storage),
-		dirtyStorage:  make(Storage),
-		onDirty:       onDirty,
+		db:             db,
+		address:        address,
+		addrHash:       sha3.Sum256(address[:]),
+		data:           data,
+		cachedStorage:  make(Storage),
+		dirtyStorage:   make(Storage),
+		pendingStorage: make(Storage),
+		onDirty:        onDirty,
 	}
 }
 
@@ -180,11 +182,17 @@ func (ao *accountObject) GetData(db AccountDatabase, key []byte) []byte {
 	if exists {
 		return value
 	}
-	// Otherwise load the value from the database
-	value, err := ao.getTrie(db).TryGet(key)
-	if err != nil {
-		ao.setError(err)
-		return nil
+	// Otherwise load the value from the flat state or the database
+	var flat bool
+	if ao.db.snaps != nil {
+		value, flat = ao.db.snaps.data(ao.db.root, ao.address, key)
+	}
+	if !flat {
+		var err error
+		if value, err = ao.getTrie(db).TryGet(key); err != nil {
+			ao.setError(err)
+			return nil
+		}
 	}
 
 	if value != nil {
@@ -214,6 +222,7 @@ func (ao *accountObject) setData(key []byte, value []byte) {
 	ao.cachedStorage[string(key)] = value
 	ao.cachedLock.Unlock()
 	ao.dirtyStorage[string(key)] = value
+	ao.pendingStorage[string(key)] = value
 
 	if ao.onDirty != nil {
 		ao.onDirty(ao.Address())
@@ -303,6 +312,7 @@ func (ao *accountObject) deepCopy(db *AccountDB, onDirty func(addr common.Addres
 	}
 	accountObject.code = ao.code
 	accountObject.dirtyStorage = ao.dirtyStorage.Copy()
+	accountObject.pendingStorage = ao.pendingStorage.Copy()
 	accountObject.cachedStorage = ao.dirtyStorage.Copy()
 	accountObject.suicided = ao.suicided
 	accountObject.dirtyCode = ao.dirtyCode
@@ -21,6 +21,7 @@ import (
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/rlp"
 	"github.com/xchain/go-chain/storage/trie"
 	"github.com/xchain/go-chain/storage/xchaindb"
 )
@@ -77,6 +78,10 @@ type Trie interface {
 	// NodeIterator returns an iterator that returns nodes of the trie. Iteration
 	// starts at the key after the given start key.
 	NodeIterator(startKey []byte) trie.NodeIterator
+
+	// Prove constructs a merkle proof for key, which contains all encoded nodes on
+	// the path to the value at key, or the nodes proving the absence of the key.
+	Prove(key []byte) ([][]byte, error)
 }
 
 // NewDatabase creates a backing store for state. The returned database
@@ -90,10 +95,25 @@ func NewDatabase(db xchaindb.Database) AccountDatabase {
 	}
 }
 
+// NewDatabaseWithSnapshots creates a backing store for state, which maintains the flat state of the
+// recent roots in the flatdb for fast reads. The flat state is regenerated from the trie of the root
+// if it isn't of the root. The flatdb should be separated from the db, as the pruner deletes the
+// keys not of the trie nodes
+func NewDatabaseWithSnapshots(db, flatdb xchaindb.Database, root common.Hash, diffLayers int) (AccountDatabase, error) {
+	sdb := NewDatabase(db).(*storageDB)
+	snaps, err := newSnapshotTree(flatdb, sdb, root, diffLayers)
+	if err != nil {
+		return nil, err
+	}
+	sdb.snaps = snaps
+	return sdb, nil
+}
+
 type storageDB struct {
 	db            *trie.NodeDatabase
 	mu            sync.Mutex
 	codeSizeCache *lru.Cache
+	snaps         *snapshotTree // Flat state of the recent roots, nil if not enabled
 }
 
 // TrieDB retrieves the low level trie database used for data storage.
@@ -150,3 +170,13 @@ func (db *storageDB) CopyTrie(t Trie) Trie {
 		panic(fmt.Errorf("unknown trie type %T", t))
 	}
 }
+
+// AccountLeafChildren returns the storage root and the code hash referenced by the account leaf
+// of the state trie, so that they are kept along with the account when pruning the state
+func AccountLeafChildren(leaf []byte) []common.Hash {
+	var data Account
+	if err := rlp.DecodeBytes(leaf, &data); err != nil {
+		return nil
+	}
+	return []common.Hash{data.Root, common.BytesToHash(data.CodeHash)}
+}
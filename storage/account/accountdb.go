@@ -48,6 +48,10 @@ var (
 type AccountDB struct {
 	db   AccountDatabase
 	trie Trie
+	root common.Hash // The root the state is opened at or committed to last
+
+	// Flat state for reading the accounts and data without resolving the trie, nil if not enabled
+	snaps *snapshotTree
 
 	accountObjects      *sync.Map
 	accountObjectsDirty map[common.Address]struct{}
@@ -81,6 +85,8 @@ func NewAccountDB(root common.Hash, db AccountDatabase) (*AccountDB, error) {
 	accountDb := &AccountDB{
 		db:                  db,
 		trie:                tr,
+		root:                root,
+		snaps:               snapshotsOf(db),
 		accountObjects:      new(sync.Map),
 		accountObjectsDirty: make(map[common.Address]struct{}),
 	}
@@ -112,6 +118,7 @@ func (adb *AccountDB) Reset(root common.Hash) error {
 		return err
 	}
 	adb.trie = tr
+	adb.root = root
 	adb.accountObjects = new(sync.Map)
 	adb.accountObjectsDirty = make(map[common.Address]struct{})
 	adb.thash = common.Hash{}
@@ -328,7 +335,17 @@ func (adb *AccountDB) deleteAccountObject(stateObject *accountObject) {
 
 // Retrieve a account object given by the address. Returns nil if not found.
 func (adb *AccountDB) getAccountObjectFromTrie(addr common.Address) (stateObject *accountObject) {
-	enc, err := adb.trie.TryGet(addr[:])
+	var (
+		enc  []byte
+		err  error
+		flat bool
+	)
+	if adb.snaps != nil {
+		enc, flat = adb.snaps.account(adb.root, addr)
+	}
+	if !flat {
+		enc, err = adb.trie.TryGet(addr[:])
+	}
 	if len(enc) == 0 {
 		adb.setError(err)
 		return nil
@@ -509,6 +526,10 @@ func (adb *AccountDB) clearJournalAndRefund() {
 func (adb *AccountDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error) {
 	defer adb.clearJournalAndRefund()
 	var e *error
+	var diff *flatDiff
+	if adb.snaps != nil {
+		diff = newFlatDiff()
+	}
 	adb.accountObjects.Range(func(key, value interface{}) bool {
 		addr := key.(common.Address)
 		_, isDirty := adb.accountObjectsDirty[addr]
@@ -516,6 +537,9 @@ func (adb *AccountDB) Commit(deleteEmptyObjects bool) (root common.Hash, err err
 		switch {
 		case accountObject.suicided || (isDirty && deleteEmptyObjects && accountObject.empty()):
 			adb.deleteAccountObject(accountObject)
+			if diff != nil {
+				diff.destruct(addr)
+			}
 		case isDirty:
 			if accountObject.code != nil && accountObject.dirtyCode {
 				adb.db.TrieDB().InsertBlob(common.BytesToHash(accountObject.CodeHash()), accountObject.code)
@@ -528,6 +552,11 @@ func (adb *AccountDB) Commit(deleteEmptyObjects bool) (root common.Hash, err err
 			}
 			// Update the object in the main account trie.
 			adb.updateAccountObject(accountObject)
+			if diff != nil {
+				enc, _ := rlp.EncodeToBytes(accountObject.data)
+				diff.update(addr, enc, accountObject.pendingStorage)
+			}
+			accountObject.pendingStorage = make(Storage)
 		}
 		delete(adb.accountObjectsDirty, addr)
 		return true
@@ -549,5 +578,14 @@ func (adb *AccountDB) Commit(deleteEmptyObjects bool) (root common.Hash, err err
 		}
 		return nil
 	})
-	return root, err
+	if err != nil {
+		return root, err
+	}
+	if diff != nil {
+		if err = adb.snaps.update(root, adb.root, diff); err != nil {
+			return root, err
+		}
+	}
+	adb.root = root
+	return root, nil
 }
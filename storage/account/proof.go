@@ -0,0 +1,84 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/rlp"
+	"github.com/xchain/go-chain/storage/trie"
+)
+
+// GetProof returns the merkle proof of the account in the state trie of the committed root.
+// The changes not committed yet are not included
+func (adb *AccountDB) GetProof(addr common.Address) ([][]byte, error) {
+	tr, err := adb.db.OpenTrie(adb.root)
+	if err != nil {
+		return nil, err
+	}
+	return tr.Prove(addr[:])
+}
+
+// GetStorageProof returns the merkle proof of the key in the storage trie of the account, which is
+// read from the state trie of the committed root. The changes not committed yet are not included
+func (adb *AccountDB) GetStorageProof(addr common.Address, key []byte) ([][]byte, error) {
+	tr, err := adb.db.OpenTrie(adb.root)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := tr.TryGet(addr[:])
+	if err != nil {
+		return nil, err
+	}
+	if len(enc) == 0 {
+		return nil, fmt.Errorf("account %v not found", addr.Hex())
+	}
+	var data Account
+	if err := rlp.DecodeBytes(enc, &data); err != nil {
+		return nil, fmt.Errorf("decode account error:%v", err)
+	}
+	storage, err := adb.db.OpenStorageTrie(sha3.Sum256(addr[:]), data.Root)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Prove(key)
+}
+
+// VerifyAccountProof checks the proof of the account against the state root and returns the account.
+// A nil account without error means the proof proves the account doesn't exist
+func VerifyAccountProof(stateRoot common.Hash, addr common.Address, proof [][]byte) (*Account, error) {
+	enc, err := trie.VerifyProof(stateRoot, addr[:], proof)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return nil, nil
+	}
+	data := new(Account)
+	if err := rlp.DecodeBytes(enc, data); err != nil {
+		return nil, fmt.Errorf("decode account error:%v", err)
+	}
+	return data, nil
+}
+
+// VerifyStorageProof checks the proof of the key against the storage root of the account, which is
+// returned by VerifyAccountProof, and returns the value. A nil value without error means the key doesn't exist
+func VerifyStorageProof(storageRoot common.Hash, key []byte, proof [][]byte) ([]byte, error) {
+	return trie.VerifyProof(storageRoot, key, proof)
+}
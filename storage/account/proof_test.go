@@ -0,0 +1,143 @@
+//   Copyright (C) 2018 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/xchaindb"
+)
+
+func TestAccountDB_Proof(t *testing.T) {
+	db, _ := xchaindb.NewMemDatabase()
+	state, _ := NewAccountDB(common.Hash{}, NewDatabase(db))
+	for i := byte(1); i < 100; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		state.AddBalance(addr, big.NewInt(int64(i)))
+		state.SetData(addr, []byte("stake"), []byte{i})
+	}
+	bare := common.BytesToAddress([]byte("bare"))
+	state.AddBalance(bare, big.NewInt(1))
+	root, err := state.Commit(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _ = NewAccountDB(root, state.Database())
+
+	addr := common.BytesToAddress([]byte{42})
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, err := VerifyAccountProof(root, addr, proof)
+	if err != nil || acc == nil {
+		t.Fatalf("verify account error:%v", err)
+	}
+	if acc.Balance.Int64() != 42 {
+		t.Fatalf("unexpected balance %v", acc.Balance)
+	}
+	storageProof, err := state.GetStorageProof(addr, []byte("stake"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := VerifyStorageProof(acc.Root, []byte("stake"), storageProof)
+	if err != nil || !bytes.Equal(value, []byte{42}) {
+		t.Fatalf("verify storage error:%v, value %x", err, value)
+	}
+	// The proof of other account can't prove the balance
+	if _, err := VerifyAccountProof(root, common.BytesToAddress([]byte{43}), proof); err == nil {
+		t.Fatalf("account proved by the proof of another")
+	}
+
+	// Absence of the account and the key
+	missing := common.BytesToAddress([]byte("missing"))
+	proof, _ = state.GetProof(missing)
+	if acc, err := VerifyAccountProof(root, missing, proof); err != nil || acc != nil {
+		t.Fatalf("absence not proved, err %v", err)
+	}
+	storageProof, _ = state.GetStorageProof(addr, []byte("missing"))
+	if value, err := VerifyStorageProof(acc.Root, []byte("missing"), storageProof); err != nil || value != nil {
+		t.Fatalf("absence of key not proved, err %v", err)
+	}
+	// Account without storage
+	proof, _ = state.GetProof(bare)
+	acc, err = VerifyAccountProof(root, bare, proof)
+	if err != nil || acc == nil {
+		t.Fatalf("verify account error:%v", err)
+	}
+	storageProof, err = state.GetStorageProof(bare, []byte("stake"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := VerifyStorageProof(acc.Root, []byte("stake"), storageProof); err != nil || value != nil {
+		t.Fatalf("absence of key in empty storage not proved, err %v", err)
+	}
+}
+
+func TestAccountDB_ProofCommittedOnly(t *testing.T) {
+	db, _ := xchaindb.NewMemDatabase()
+	state, _ := NewAccountDB(common.Hash{}, NewDatabase(db))
+	addr := common.BytesToAddress([]byte("committed"))
+	state.AddBalance(addr, big.NewInt(1))
+	state.SetData(addr, []byte("key"), []byte("committed"))
+	root, err := state.Commit(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _ = NewAccountDB(root, state.Database())
+
+	// Uncommitted writes, even hashed into the tries, are not proved
+	state.AddBalance(addr, big.NewInt(1))
+	state.SetData(addr, []byte("key"), []byte("dirty"))
+	state.IntermediateRoot(true)
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, err := VerifyAccountProof(root, addr, proof)
+	if err != nil || acc == nil || acc.Balance.Int64() != 1 {
+		t.Fatalf("verify account error:%v", err)
+	}
+	storageProof, err := state.GetStorageProof(addr, []byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := VerifyStorageProof(acc.Root, []byte("key"), storageProof); err != nil || !bytes.Equal(value, []byte("committed")) {
+		t.Fatalf("verify storage error:%v, value %s", err, value)
+	}
+	// The account created after the commit is not found
+	if _, err := state.GetStorageProof(common.BytesToAddress([]byte("new")), []byte("key")); err == nil {
+		t.Fatalf("uncommitted account should not be proved")
+	}
+	// Both forms of the empty storage root prove the absence
+	for _, root := range []common.Hash{{}, emptyStorageRoot(t)} {
+		if value, err := VerifyStorageProof(root, []byte("key"), nil); err != nil || value != nil {
+			t.Fatalf("absence in empty storage %v not proved, err %v", root.Hex(), err)
+		}
+	}
+}
+
+func emptyStorageRoot(t *testing.T) common.Hash {
+	db, _ := xchaindb.NewMemDatabase()
+	tr, err := NewDatabase(db).OpenStorageTrie(common.Hash{}, common.Hash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr.Hash()
+}
@@ -0,0 +1,255 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/rlp"
+)
+
+const (
+	// SnapshotVersion is the version of the snapshot file format
+	SnapshotVersion uint32 = 1
+
+	// snapshotCommitInterval is the number of the accounts imported before flushing to the disk
+	snapshotCommitInterval = 10000
+)
+
+var snapshotMagic = []byte("XSNP")
+
+// SnapshotHeader identifies the block the state snapshot is taken at
+type SnapshotHeader struct {
+	Height    uint64
+	BlockHash common.Hash
+	StateTree common.Hash
+}
+
+// snapshotAccount is the full content of an account in the snapshot
+type snapshotAccount struct {
+	Address common.Address
+	Nonce   uint64
+	Balance *big.Int
+	Code    []byte
+	Storage []snapshotData
+}
+
+type snapshotData struct {
+	Key   []byte
+	Value []byte
+}
+
+// The snapshot file is laid out as
+//
+//	magic | version | rlp(header) | rlp(account)... | rlp("") | sha256 checksum
+//
+// The checksum covers all the preceding bytes.
+
+// ExportSnapshot writes the accounts of the state, with their code and data, to the writer.
+// The state must be opened at the state root of the header
+func ExportSnapshot(w io.Writer, state *AccountDB, header SnapshotHeader) (accounts int, err error) {
+	hasher := sha256.New()
+	bw := bufio.NewWriter(io.MultiWriter(w, hasher))
+
+	version := make([]byte, 4)
+	binary.BigEndian.PutUint32(version, SnapshotVersion)
+	bw.Write(snapshotMagic)
+	bw.Write(version)
+	if err = rlp.Encode(bw, &header); err != nil {
+		return
+	}
+
+	err = iterateAccounts(state, func(acc *snapshotAccount, _ []byte) error {
+		accounts++
+		return rlp.Encode(bw, acc)
+	})
+	if err != nil {
+		return
+	}
+	// The empty string terminates the accounts
+	if err = rlp.Encode(bw, []byte{}); err != nil {
+		return
+	}
+	if err = bw.Flush(); err != nil {
+		return
+	}
+	_, err = w.Write(hasher.Sum(nil))
+	return
+}
+
+// iterateAccounts walks the state trie and calls the fn with the full content of each account,
+// along with the account encoded in the trie
+func iterateAccounts(state *AccountDB, fn func(acc *snapshotAccount, blob []byte) error) error {
+	var (
+		current *snapshotAccount
+		blob    []byte
+	)
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		return fn(current, blob)
+	}
+	it := NewNodeIterator(state)
+	for it.Next() {
+		// The account leaf is reached before its data and code
+		if it.stateIt.Leaf() && (current == nil || !bytes.Equal(current.Address[:], it.stateIt.LeafKey())) {
+			if err := flush(); err != nil {
+				return err
+			}
+			var data Account
+			blob = common.CopyBytes(it.stateIt.LeafBlob())
+			if err := rlp.DecodeBytes(blob, &data); err != nil {
+				return err
+			}
+			current = &snapshotAccount{
+				Address: common.BytesToAddress(it.stateIt.LeafKey()),
+				Nonce:   data.Nonce,
+				Balance: data.Balance,
+			}
+		}
+		switch {
+		case it.dataIt != nil:
+			if it.dataIt.Leaf() {
+				current.Storage = append(current.Storage, snapshotData{
+					Key:   common.CopyBytes(it.dataIt.LeafKey()),
+					Value: common.CopyBytes(it.dataIt.LeafBlob()),
+				})
+			}
+		case it.code != nil:
+			current.Code = it.code
+		}
+	}
+	if it.Error != nil {
+		return it.Error
+	}
+	return flush()
+}
+
+// ImportSnapshot rebuilds the state from the snapshot into the database, which should be empty.
+// An error is returned if the snapshot is corrupted or the rebuilt state root doesn't match the header
+func ImportSnapshot(r io.Reader, db AccountDatabase) (header SnapshotHeader, accounts int, err error) {
+	br := bufio.NewReader(r)
+	hr := &hashingReader{r: br, hasher: sha256.New()}
+
+	prefix := make([]byte, len(snapshotMagic)+4)
+	if _, err = io.ReadFull(hr, prefix); err != nil {
+		return
+	}
+	if !bytes.Equal(prefix[:len(snapshotMagic)], snapshotMagic) {
+		err = fmt.Errorf("not a state snapshot")
+		return
+	}
+	if version := binary.BigEndian.Uint32(prefix[len(snapshotMagic):]); version != SnapshotVersion {
+		err = fmt.Errorf("unsupported snapshot version %v, expect %v", version, SnapshotVersion)
+		return
+	}
+	stream := rlp.NewStream(hr, 0)
+	if err = stream.Decode(&header); err != nil {
+		return
+	}
+
+	state, err := NewAccountDB(common.Hash{}, db)
+	if err != nil {
+		return
+	}
+	root := common.Hash{}
+	commit := func() error {
+		// Keep the empty accounts, they are in the snapshot because they exist in the state
+		if root, err = state.Commit(false); err != nil {
+			return err
+		}
+		if err = db.TrieDB().Commit(root, false); err != nil {
+			return err
+		}
+		state, err = NewAccountDB(root, db)
+		return err
+	}
+	for {
+		kind, size, e := stream.Kind()
+		if e != nil {
+			err = e
+			return
+		}
+		if kind == rlp.String && size == 0 {
+			stream.Bytes()
+			break
+		}
+		var acc snapshotAccount
+		if err = stream.Decode(&acc); err != nil {
+			return
+		}
+		state.CreateAccount(acc.Address)
+		state.SetNonce(acc.Address, acc.Nonce)
+		state.SetBalance(acc.Address, acc.Balance)
+		if len(acc.Code) > 0 {
+			state.SetCode(acc.Address, acc.Code)
+		}
+		for _, d := range acc.Storage {
+			state.SetData(acc.Address, d.Key, d.Value)
+		}
+		accounts++
+		if accounts%snapshotCommitInterval == 0 {
+			if err = commit(); err != nil {
+				return
+			}
+		}
+	}
+
+	checksum := make([]byte, sha256.Size)
+	if _, err = io.ReadFull(br, checksum); err != nil {
+		return
+	}
+	if !bytes.Equal(checksum, hr.hasher.Sum(nil)) {
+		err = fmt.Errorf("snapshot checksum mismatch")
+		return
+	}
+	if err = commit(); err != nil {
+		return
+	}
+	if root != header.StateTree {
+		err = fmt.Errorf("state root mismatch, expect %v, got %v", header.StateTree.Hex(), root.Hex())
+	}
+	return
+}
+
+// hashingReader hashes the bytes consumed from the underlying reader
+type hashingReader struct {
+	r      *bufio.Reader
+	hasher hash.Hash
+}
+
+func (hr *hashingReader) Read(p []byte) (int, error) {
+	n, err := hr.r.Read(p)
+	hr.hasher.Write(p[:n])
+	return n, err
+}
+
+func (hr *hashingReader) ReadByte() (byte, error) {
+	b, err := hr.r.ReadByte()
+	if err == nil {
+		hr.hasher.Write([]byte{b})
+	}
+	return b, err
+}
@@ -0,0 +1,115 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/xchaindb"
+)
+
+func newSnapshotTestState(t *testing.T) (*AccountDB, SnapshotHeader) {
+	db, _ := xchaindb.NewMemDatabase()
+	state, _ := NewAccountDB(common.Hash{}, NewDatabase(db))
+	for i := byte(1); i < 200; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		state.AddBalance(addr, big.NewInt(int64(i)*1000))
+		state.SetNonce(addr, uint64(i))
+		if i%3 == 0 {
+			state.SetData(addr, []byte("stake"), []byte{i})
+			state.SetData(addr, []byte{i, i}, bytes.Repeat([]byte{i}, 40))
+		}
+	}
+	contract := common.BytesToAddress([]byte("contract"))
+	state.SetCode(contract, []byte("contract code"))
+	state.SetData(contract, []byte("key"), []byte("value"))
+	// Account with data only
+	state.SetData(common.BytesToAddress([]byte("escrow")), []byte("key"), []byte("value"))
+
+	root, err := state.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Database().TrieDB().Commit(root, false)
+	state, _ = NewAccountDB(root, state.Database())
+	return state, SnapshotHeader{Height: 100, BlockHash: common.BytesToHash([]byte("block")), StateTree: root}
+}
+
+func TestSnapshot_ExportImport(t *testing.T) {
+	state, header := newSnapshotTestState(t)
+	buf := new(bytes.Buffer)
+	accounts, err := ExportSnapshot(buf, state, header)
+	if err != nil {
+		t.Fatalf("export error:%v", err)
+	}
+	if accounts != 201 {
+		t.Fatalf("unexpected accounts %v", accounts)
+	}
+
+	db, _ := xchaindb.NewMemDatabase()
+	h, imported, err := ImportSnapshot(bytes.NewReader(buf.Bytes()), NewDatabase(db))
+	if err != nil {
+		t.Fatalf("import error:%v", err)
+	}
+	if h != header || imported != accounts {
+		t.Fatalf("unexpected header %+v or accounts %v", h, imported)
+	}
+	rebuilt, err := NewAccountDB(header.StateTree, NewDatabase(db))
+	if err != nil {
+		t.Fatalf("open rebuilt state error:%v", err)
+	}
+	addr := common.BytesToAddress([]byte{42})
+	if rebuilt.GetBalance(addr).Int64() != 42000 || rebuilt.GetNonce(addr) != 42 || !bytes.Equal(rebuilt.GetData(addr, []byte("stake")), []byte{42}) {
+		t.Fatalf("rebuilt account mismatch")
+	}
+	if !bytes.Equal(rebuilt.GetCode(common.BytesToAddress([]byte("contract"))), []byte("contract code")) {
+		t.Fatalf("rebuilt code mismatch")
+	}
+}
+
+func TestSnapshot_Corrupted(t *testing.T) {
+	state, header := newSnapshotTestState(t)
+	buf := new(bytes.Buffer)
+	if _, err := ExportSnapshot(buf, state, header); err != nil {
+		t.Fatalf("export error:%v", err)
+	}
+	data := buf.Bytes()
+
+	corrupted := common.CopyBytes(data)
+	corrupted[len(corrupted)/2] ^= 0xff
+	db, _ := xchaindb.NewMemDatabase()
+	if _, _, err := ImportSnapshot(bytes.NewReader(corrupted), NewDatabase(db)); err == nil {
+		t.Fatalf("corrupted snapshot imported")
+	}
+
+	truncated := data[:len(data)-1]
+	db, _ = xchaindb.NewMemDatabase()
+	if _, _, err := ImportSnapshot(bytes.NewReader(truncated), NewDatabase(db)); err == nil {
+		t.Fatalf("truncated snapshot imported")
+	}
+
+	// The snapshot of another state
+	header.StateTree = common.BytesToHash([]byte("root"))
+	buf.Reset()
+	ExportSnapshot(buf, state, header)
+	db, _ = xchaindb.NewMemDatabase()
+	if _, _, err := ImportSnapshot(buf, NewDatabase(db)); err == nil {
+		t.Fatalf("snapshot with wrong root imported")
+	}
+}
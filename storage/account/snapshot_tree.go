@@ -0,0 +1,321 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"sync"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/xchaindb"
+)
+
+// DefaultSnapshotDiffLayers is the number of the recent states kept in the memory as diff layers
+const DefaultSnapshotDiffLayers = 128
+
+var (
+	flatRootKey       = []byte("FlatRoot") // The state root of the disk layer
+	flatAccountPrefix = []byte("fa")       // flatAccountPrefix + address -> account
+	flatDataPrefix    = []byte("fd")       // flatDataPrefix + address + key -> value
+)
+
+func flatAccountKey(addr common.Address) []byte {
+	return append(common.CopyBytes(flatAccountPrefix), addr[:]...)
+}
+
+func flatDataKey(addr common.Address, key []byte) []byte {
+	return append(append(common.CopyBytes(flatDataPrefix), addr[:]...), key...)
+}
+
+// flatDiff is the changes of the accounts committed in a state transition
+type flatDiff struct {
+	destructs map[common.Address]struct{}          // The accounts deleted, with all their data
+	accounts  map[common.Address][]byte            // The encoded accounts, nil if deleted
+	storage   map[common.Address]map[string][]byte // The data of the accounts, nil if deleted
+}
+
+func newFlatDiff() *flatDiff {
+	return &flatDiff{
+		destructs: make(map[common.Address]struct{}),
+		accounts:  make(map[common.Address][]byte),
+		storage:   make(map[common.Address]map[string][]byte),
+	}
+}
+
+func (d *flatDiff) destruct(addr common.Address) {
+	d.destructs[addr] = struct{}{}
+	d.accounts[addr] = nil
+	delete(d.storage, addr)
+}
+
+func (d *flatDiff) update(addr common.Address, account []byte, storage Storage) {
+	d.accounts[addr] = account
+	if len(storage) == 0 {
+		return
+	}
+	data, ok := d.storage[addr]
+	if !ok {
+		data = make(map[string][]byte, len(storage))
+		d.storage[addr] = data
+	}
+	for k, v := range storage {
+		data[k] = v
+	}
+}
+
+// diffLayer is the flat state of a root on top of its parent layer
+type diffLayer struct {
+	*flatDiff
+	root   common.Hash
+	parent common.Hash
+}
+
+// snapshotTree maintains the flat state of the recent roots for reading the accounts and data without
+// resolving the trie nodes. The state of the oldest root is persisted in the disk database, the disk
+// layer, and each root committed after it is kept in the memory as a diff layer to its parent, so the
+// states of the forks can be read as well. Once there are more diff layers than the limit, the bottom
+// one is flattened into the disk layer and the forks not built on it are dropped.
+//
+// The roots without the flat state are read from the trie.
+type snapshotTree struct {
+	diskdb     xchaindb.Database
+	diskRoot   common.Hash
+	diffs      map[common.Hash]*diffLayer
+	diffLayers int
+	lock       sync.RWMutex
+}
+
+// newSnapshotTree loads the flat state of the disk database, or regenerates it from the trie if it isn't
+// of the root. The disk database should be dedicated to the flat state
+func newSnapshotTree(diskdb xchaindb.Database, db AccountDatabase, root common.Hash, diffLayers int) (*snapshotTree, error) {
+	t := &snapshotTree{
+		diskdb:     diskdb,
+		diffs:      make(map[common.Hash]*diffLayer),
+		diffLayers: diffLayers,
+	}
+	if enc, err := diskdb.Get(flatRootKey); err == nil && common.BytesToHash(enc) == root {
+		t.diskRoot = root
+		return t, nil
+	}
+	if err := t.generate(db, root); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// generate rebuilds the disk layer from the trie of the root
+func (t *snapshotTree) generate(db AccountDatabase, root common.Hash) error {
+	state, err := NewAccountDB(root, db)
+	if err != nil {
+		return err
+	}
+	batch := t.diskdb.NewBatch()
+	write := func() error {
+		if batch.ValueSize() < xchaindb.IdealBatchSize {
+			return nil
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	}
+
+	// Wipe out the stale flat state first
+	iter := t.diskdb.NewIterator()
+	for iter.Next() {
+		batch.Delete(common.CopyBytes(iter.Key()))
+		if err = write(); err != nil {
+			break
+		}
+	}
+	iter.Release()
+	if err != nil {
+		return err
+	}
+
+	err = iterateAccounts(state, func(acc *snapshotAccount, blob []byte) error {
+		batch.Put(flatAccountKey(acc.Address), blob)
+		for _, d := range acc.Storage {
+			batch.Put(flatDataKey(acc.Address, d.Key), d.Value)
+		}
+		return write()
+	})
+	if err != nil {
+		return err
+	}
+	batch.Put(flatRootKey, root[:])
+	if err = batch.Write(); err != nil {
+		return err
+	}
+	t.diskRoot = root
+	return nil
+}
+
+// has returns whether the flat state of the root is available
+func (t *snapshotTree) has(root common.Hash) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	_, ok := t.diffs[root]
+	return ok || root == t.diskRoot
+}
+
+// account returns the encoded account of the address in the state of the root, nil if the account doesn't
+// exist. False is returned if the flat state of the root isn't available
+func (t *snapshotTree) account(root common.Hash, addr common.Address) ([]byte, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for root != t.diskRoot {
+		diff, ok := t.diffs[root]
+		if !ok {
+			return nil, false
+		}
+		if enc, ok := diff.accounts[addr]; ok {
+			return enc, true
+		}
+		root = diff.parent
+	}
+	return t.diskGet(flatAccountKey(addr))
+}
+
+// data returns the value of the key in the account data in the state of the root, nil if not found.
+// False is returned if the flat state of the root isn't available
+func (t *snapshotTree) data(root common.Hash, addr common.Address, key []byte) ([]byte, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for root != t.diskRoot {
+		diff, ok := t.diffs[root]
+		if !ok {
+			return nil, false
+		}
+		if value, ok := diff.storage[addr][string(key)]; ok {
+			return value, true
+		}
+		if _, ok := diff.destructs[addr]; ok {
+			return nil, true
+		}
+		root = diff.parent
+	}
+	return t.diskGet(flatDataKey(addr, key))
+}
+
+func (t *snapshotTree) diskGet(key []byte) ([]byte, bool) {
+	value, err := t.diskdb.Get(key)
+	if err == nil {
+		return value, true
+	}
+	// Distinguish the missing key from the database failure
+	if has, err := t.diskdb.Has(key); err == nil && !has {
+		return nil, true
+	}
+	return nil, false
+}
+
+// update adds the diff layer of the root committed on the parent, and flattens the bottom diff
+// layers into the disk layer if there are too many
+func (t *snapshotTree) update(root, parent common.Hash, diff *flatDiff) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.diffs[root]; ok || root == parent || root == t.diskRoot {
+		return nil
+	}
+	if _, ok := t.diffs[parent]; !ok && parent != t.diskRoot {
+		// The parent is pruned or was never tracked, leave the root to the trie
+		return nil
+	}
+	t.diffs[root] = &diffLayer{flatDiff: diff, root: root, parent: parent}
+
+	for {
+		layers := make([]*diffLayer, 0, t.diffLayers+1)
+		for r := root; r != t.diskRoot; r = t.diffs[r].parent {
+			layers = append(layers, t.diffs[r])
+		}
+		if len(layers) <= t.diffLayers {
+			return nil
+		}
+		if err := t.flatten(layers[len(layers)-1]); err != nil {
+			return err
+		}
+	}
+}
+
+// flatten merges the diff layer on the disk layer into it, and drops the layers not built on it
+func (t *snapshotTree) flatten(bottom *diffLayer) error {
+	batch := t.diskdb.NewBatch()
+	for addr := range bottom.destructs {
+		prefix := flatDataKey(addr, nil)
+		iter := t.diskdb.NewIteratorWithPrefix(prefix)
+		for iter.Next() {
+			batch.Delete(common.CopyBytes(iter.Key()))
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
+	}
+	for addr, enc := range bottom.accounts {
+		if enc == nil {
+			batch.Delete(flatAccountKey(addr))
+		} else {
+			batch.Put(flatAccountKey(addr), enc)
+		}
+	}
+	for addr, data := range bottom.storage {
+		for k, v := range data {
+			if len(v) == 0 {
+				batch.Delete(flatDataKey(addr, []byte(k)))
+			} else {
+				batch.Put(flatDataKey(addr, []byte(k)), v)
+			}
+		}
+	}
+	batch.Put(flatRootKey, bottom.root[:])
+	if err := batch.Write(); err != nil {
+		return err
+	}
+
+	t.diskRoot = bottom.root
+	delete(t.diffs, bottom.root)
+	// Drop the forks on the previous disk layer
+	for root := range t.diffs {
+		if !t.builtOnDisk(root) {
+			delete(t.diffs, root)
+		}
+	}
+	return nil
+}
+
+func (t *snapshotTree) builtOnDisk(root common.Hash) bool {
+	for root != t.diskRoot {
+		diff, ok := t.diffs[root]
+		if !ok {
+			return false
+		}
+		root = diff.parent
+	}
+	return true
+}
+
+// snapshotsOf returns the flat state maintained by the database, nil if not enabled
+func snapshotsOf(db AccountDatabase) *snapshotTree {
+	if sdb, ok := db.(*storageDB); ok {
+		return sdb.snaps
+	}
+	return nil
+}
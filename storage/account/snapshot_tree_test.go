@@ -0,0 +1,238 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/xchaindb"
+)
+
+var (
+	flatTestKey  = []byte("key")
+	flatFixedKey = []byte("fixed") // Only written in the first block, and gone with the account
+)
+
+func newFlatTestDatabase(t testing.TB, diffLayers int) (AccountDatabase, AccountDatabase) {
+	db, _ := xchaindb.NewMemDatabase()
+	flatdb, _ := xchaindb.NewMemDatabase()
+	sdb, err := NewDatabaseWithSnapshots(db, flatdb, common.Hash{}, diffLayers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The trie only database on the same nodes for comparison
+	return sdb, NewDatabase(db)
+}
+
+// commitFlatTestBlock modifies the accounts on the state of the parent root, and returns the committed root
+func commitFlatTestBlock(t testing.TB, db AccountDatabase, parent common.Hash, seed byte) common.Hash {
+	state, err := NewAccountDB(parent, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := byte(0); i < 16; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		state.AddBalance(addr, big.NewInt(int64(seed)))
+		if seed == 1 {
+			state.SetData(addr, flatFixedKey, []byte{i})
+		}
+		if (i+seed)%3 == 0 {
+			state.SetData(addr, flatTestKey, []byte{seed, i})
+		}
+		if (i+seed)%5 == 0 {
+			state.RemoveData(addr, flatTestKey)
+		}
+	}
+	if seed%4 == 0 {
+		state.Suicide(common.BytesToAddress([]byte{seed % 16}))
+	}
+	root, err := state.Commit(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.TrieDB().Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// checkFlatState compares the flat state of the root with the trie
+func checkFlatState(t *testing.T, sdb, triedb AccountDatabase, root common.Hash) {
+	if !snapshotsOf(sdb).has(root) {
+		t.Fatalf("flat state of %v not available", root.Hex())
+	}
+	flat, err := NewAccountDB(root, sdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _ := NewAccountDB(root, triedb)
+	for i := byte(0); i < 16; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		if flat.Exist(addr) != state.Exist(addr) || flat.GetBalance(addr).Cmp(state.GetBalance(addr)) != 0 {
+			t.Fatalf("account %v mismatch at %v", i, root.Hex())
+		}
+		for _, key := range [][]byte{flatTestKey, flatFixedKey} {
+			if !bytes.Equal(flat.GetData(addr, key), state.GetData(addr, key)) {
+				t.Fatalf("data %s of account %v mismatch at %v, got %x, expect %x", key, i, root.Hex(), flat.GetData(addr, key), state.GetData(addr, key))
+			}
+		}
+	}
+}
+
+func TestSnapshotTree_Commit(t *testing.T) {
+	sdb, triedb := newFlatTestDatabase(t, 4)
+	snaps := snapshotsOf(sdb)
+
+	roots := []common.Hash{{}}
+	for seed := byte(1); seed <= 12; seed++ {
+		roots = append(roots, commitFlatTestBlock(t, sdb, roots[len(roots)-1], seed))
+		checkFlatState(t, sdb, triedb, roots[len(roots)-1])
+	}
+	// The recent roots are in the diff layers, and the older ones are flattened
+	for i := len(roots) - 5; i < len(roots); i++ {
+		checkFlatState(t, sdb, triedb, roots[i])
+	}
+	if snaps.diskRoot != roots[len(roots)-5] || len(snaps.diffs) != 4 {
+		t.Fatalf("unexpected layers, disk root %v, diffs %v", snaps.diskRoot.Hex(), len(snaps.diffs))
+	}
+	if snaps.has(roots[1]) {
+		t.Fatalf("flattened root should not be available")
+	}
+	// The roots without flat state are read from the trie
+	state, err := NewAccountDB(roots[1], sdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.GetBalance(common.BytesToAddress([]byte{1})).Int64() != 1 {
+		t.Fatalf("balance read from trie mismatch")
+	}
+}
+
+func TestSnapshotTree_Reorg(t *testing.T) {
+	sdb, triedb := newFlatTestDatabase(t, 4)
+	snaps := snapshotsOf(sdb)
+
+	base := commitFlatTestBlock(t, sdb, common.Hash{}, 1)
+	a1 := commitFlatTestBlock(t, sdb, base, 2)
+	a2 := commitFlatTestBlock(t, sdb, a1, 3)
+	// The fork on the base
+	b1 := commitFlatTestBlock(t, sdb, base, 7)
+	b2 := commitFlatTestBlock(t, sdb, b1, 8)
+	for _, root := range []common.Hash{a1, a2, b1, b2} {
+		checkFlatState(t, sdb, triedb, root)
+	}
+
+	// Extending the fork b flattens the base, a1 and a2 are kept as they are built on it
+	b3 := commitFlatTestBlock(t, sdb, b2, 9)
+	b4 := commitFlatTestBlock(t, sdb, b3, 10)
+	if snaps.diskRoot != base {
+		t.Fatalf("unexpected disk root")
+	}
+	// Flattening b1 drops the fork a
+	commitFlatTestBlock(t, sdb, b4, 11)
+	if snaps.diskRoot != b1 {
+		t.Fatalf("unexpected disk root")
+	}
+	if snaps.has(a1) || snaps.has(a2) {
+		t.Fatalf("the fork not built on the disk layer should be dropped")
+	}
+	for _, root := range []common.Hash{b1, b2, b3, b4} {
+		checkFlatState(t, sdb, triedb, root)
+	}
+}
+
+func TestSnapshotTree_Generate(t *testing.T) {
+	db, _ := xchaindb.NewMemDatabase()
+	triedb := NewDatabase(db)
+	root := common.Hash{}
+	for seed := byte(1); seed <= 5; seed++ {
+		root = commitFlatTestBlock(t, triedb, root, seed)
+	}
+
+	flatdb, _ := xchaindb.NewMemDatabase()
+	flatdb.Put(flatDataKey(common.BytesToAddress([]byte("stale")), flatTestKey), []byte("stale"))
+	sdb, err := NewDatabaseWithSnapshots(db, flatdb, root, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFlatState(t, sdb, triedb, root)
+	if has, _ := flatdb.Has(flatDataKey(common.BytesToAddress([]byte("stale")), flatTestKey)); has {
+		t.Fatalf("stale flat state not wiped")
+	}
+
+	// Reopened on the same root without regeneration
+	flatdb.Put(flatDataKey(common.BytesToAddress([]byte{1}), []byte("marker")), []byte("marker"))
+	sdb, _ = NewDatabaseWithSnapshots(db, flatdb, root, 4)
+	checkFlatState(t, sdb, triedb, root)
+	if has, _ := flatdb.Has(flatDataKey(common.BytesToAddress([]byte{1}), []byte("marker"))); !has {
+		t.Fatalf("flat state of the root regenerated")
+	}
+}
+
+const benchFlatAccounts = 10000
+
+func newBenchFlatState(b *testing.B, flat bool) (AccountDatabase, common.Hash) {
+	db, _ := xchaindb.NewMemDatabase()
+	flatdb, _ := xchaindb.NewMemDatabase()
+	sdb, _ := NewDatabaseWithSnapshots(db, flatdb, common.Hash{}, DefaultSnapshotDiffLayers)
+	state, _ := NewAccountDB(common.Hash{}, sdb)
+	for i := 0; i < benchFlatAccounts; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i)))
+		state.AddBalance(addr, big.NewInt(int64(i+1)))
+		state.SetData(addr, flatTestKey, []byte{byte(i)})
+	}
+	root, _ := state.Commit(true)
+	sdb.TrieDB().Commit(root, false)
+	if !flat {
+		return NewDatabase(db), root
+	}
+	return sdb, root
+}
+
+func benchmarkGetBalance(b *testing.B, flat bool) {
+	db, root := newBenchFlatState(b, flat)
+	b.ResetTimer()
+
+	var state *AccountDB
+	for i := 0; i < b.N; i++ {
+		// Reopen the state to read from the database instead of the cached objects
+		if i%benchFlatAccounts == 0 {
+			state, _ = NewAccountDB(root, db)
+		}
+		state.GetBalance(common.BigToAddress(big.NewInt(int64(i % benchFlatAccounts))))
+	}
+}
+
+func benchmarkGetData(b *testing.B, flat bool) {
+	db, root := newBenchFlatState(b, flat)
+	b.ResetTimer()
+
+	var state *AccountDB
+	for i := 0; i < b.N; i++ {
+		if i%benchFlatAccounts == 0 {
+			state, _ = NewAccountDB(root, db)
+		}
+		state.GetData(common.BigToAddress(big.NewInt(int64(i%benchFlatAccounts))), flatTestKey)
+	}
+}
+
+func BenchmarkAccountDB_GetBalanceTrie(b *testing.B) { benchmarkGetBalance(b, false) }
+func BenchmarkAccountDB_GetBalanceFlat(b *testing.B) { benchmarkGetBalance(b, true) }
+func BenchmarkAccountDB_GetDataTrie(b *testing.B)    { benchmarkGetData(b, false) }
+func BenchmarkAccountDB_GetDataFlat(b *testing.B)    { benchmarkGetData(b, true) }
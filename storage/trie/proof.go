@@ -0,0 +1,162 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/rlp"
+	"github.com/xchain/go-chain/storage/sha3"
+)
+
+// Prove constructs a merkle proof for key. The result contains all encoded nodes
+// on the path to the value at key. The value itself is also included in the last
+// node and can be retrieved by verifying the proof.
+//
+// If the trie does not contain a value for key, the returned proof contains all
+// nodes of the longest existing prefix of the key (at least the root node), ending
+// with the node that proves the absence of the key.
+func (t *Trie) Prove(key []byte) ([][]byte, error) {
+	// Collect all nodes on the path to key.
+	key = keybytesToHex(key)
+	nodes := []node{}
+	tn := t.root
+	for len(key) > 0 && tn != nil {
+		switch n := tn.(type) {
+		case *shortNode:
+			if len(key) < len(n.Key) || !bytes.Equal(n.Key, key[:len(n.Key)]) {
+				// The trie doesn't contain the key.
+				tn = nil
+			} else {
+				tn = n.Val
+				key = key[len(n.Key):]
+			}
+			nodes = append(nodes, n)
+		case *fullNode:
+			tn = n.Children[key[0]]
+			key = key[1:]
+			nodes = append(nodes, n)
+		case hashNode:
+			var err error
+			tn, err = t.resolveHash(n, nil)
+			if err != nil {
+				return nil, err
+			}
+		case valueNode:
+			tn = nil
+		default:
+			return nil, fmt.Errorf("%T: invalid node: %v", tn, tn)
+		}
+	}
+	hasher := newHasher(0, 0, nil)
+	defer returnHasherToPool(hasher)
+
+	proof := make([][]byte, 0, len(nodes))
+	for i, n := range nodes {
+		// Don't bother checking for errors here since hasher panics
+		// if encoding doesn't work and we're not writing to any database.
+		n, _, _ = hasher.hashChildren(n, nil)
+		hn, _ := hasher.store(n, nil, false)
+		if _, ok := hn.(hashNode); ok || i == 0 {
+			// If the node's database encoding is a hash (or is the
+			// root node), it becomes a proof element.
+			enc, _ := rlp.EncodeToBytes(n)
+			proof = append(proof, enc)
+		}
+	}
+	return proof, nil
+}
+
+// IsEmptyRoot reports whether the root is of the empty trie, which is either the zero hash
+// of the account never having storage or the hash of the empty node
+func IsEmptyRoot(root common.Hash) bool {
+	return root == (common.Hash{}) || root == emptyRoot
+}
+
+// VerifyProof checks merkle proofs. The given proof must contain the value for
+// key in a trie with the given root hash. VerifyProof returns an error if the
+// proof contains invalid trie nodes or the wrong value. A nil value without error
+// means the proof proves the absence of the key. The empty trie contains no key, so no proof is needed.
+func VerifyProof(rootHash common.Hash, key []byte, proof [][]byte) (value []byte, err error) {
+	if IsEmptyRoot(rootHash) {
+		return nil, nil
+	}
+	nodes := make(map[common.Hash][]byte, len(proof))
+	sha := sha3.NewKeccak256()
+	for _, enc := range proof {
+		sha.Reset()
+		sha.Write(enc)
+		nodes[common.BytesToHash(sha.Sum(nil))] = enc
+	}
+
+	key = keybytesToHex(key)
+	wantHash := rootHash
+	for i := 0; ; i++ {
+		buf := nodes[wantHash]
+		if buf == nil {
+			return nil, fmt.Errorf("proof node %d (hash %064x) missing", i, wantHash)
+		}
+		n, err := decodeNode(wantHash[:], buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("bad proof node %d: %v", i, err)
+		}
+		keyrest, cld, err := get(n, key)
+		if err != nil {
+			return nil, fmt.Errorf("bad proof node %d: %v", i, err)
+		}
+		switch cld := cld.(type) {
+		case nil:
+			// The trie doesn't contain the key.
+			return nil, nil
+		case hashNode:
+			key = keyrest
+			copy(wantHash[:], cld)
+		case valueNode:
+			return cld, nil
+		}
+	}
+}
+
+// get walks the decoded node along the key until it reaches a hash node, a value or nothing
+func get(tn node, key []byte) ([]byte, node, error) {
+	for {
+		switch n := tn.(type) {
+		case *shortNode:
+			if len(key) < len(n.Key) || !bytes.Equal(n.Key, key[:len(n.Key)]) {
+				return nil, nil, nil
+			}
+			tn = n.Val
+			key = key[len(n.Key):]
+		case *fullNode:
+			if len(key) == 0 {
+				return nil, nil, fmt.Errorf("key ends at full node")
+			}
+			tn = n.Children[key[0]]
+			key = key[1:]
+		case hashNode:
+			return key, n, nil
+		case nil:
+			return key, nil, nil
+		case valueNode:
+			return nil, n, nil
+		default:
+			return nil, nil, fmt.Errorf("%T: invalid node: %v", tn, tn)
+		}
+	}
+}
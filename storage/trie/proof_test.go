@@ -0,0 +1,155 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	mrand "math/rand"
+	"testing"
+
+	"github.com/xchain/go-chain/common"
+)
+
+func randomTrie(n int) (*Trie, map[string][]byte) {
+	trie := newEmpty()
+	vals := make(map[string][]byte)
+	for i := byte(0); i < 100; i++ {
+		k1 := append(make([]byte, 31), i)
+		k2 := append(make([]byte, 31), i+10)
+		trie.Update(k1, []byte{i})
+		trie.Update(k2, []byte{i})
+		vals[string(k1)] = []byte{i}
+		vals[string(k2)] = []byte{i}
+	}
+	for i := 0; i < n; i++ {
+		k := randBytes(32)
+		v := randBytes(20)
+		trie.Update(k, v)
+		vals[string(k)] = v
+	}
+	return trie, vals
+}
+
+func randBytes(n int) []byte {
+	r := make([]byte, n)
+	crand.Read(r)
+	return r
+}
+
+func TestProof(t *testing.T) {
+	trie, vals := randomTrie(500)
+	root := trie.Hash()
+	for k, v := range vals {
+		proof, err := trie.Prove([]byte(k))
+		if err != nil {
+			t.Fatalf("prove %x error:%v", k, err)
+		}
+		val, err := VerifyProof(root, []byte(k), proof)
+		if err != nil {
+			t.Fatalf("VerifyProof error for key %x: %v\nraw proof: %x", k, err, proof)
+		}
+		if !bytes.Equal(val, v) {
+			t.Fatalf("VerifyProof returned wrong value for key %x: got %x, want %x", k, val, v)
+		}
+	}
+}
+
+func TestOneElementProof(t *testing.T) {
+	trie := newEmpty()
+	trie.Update([]byte("k"), []byte("v"))
+	proof, _ := trie.Prove([]byte("k"))
+	if len(proof) != 1 {
+		t.Error("proof should have one element")
+	}
+	val, err := VerifyProof(trie.Hash(), []byte("k"), proof)
+	if err != nil {
+		t.Fatalf("VerifyProof error: %v\nproof hashes: %x", err, proof)
+	}
+	if !bytes.Equal(val, []byte("v")) {
+		t.Fatalf("VerifyProof returned wrong value: got %x, want 'v'", val)
+	}
+}
+
+func TestMissingKeyProof(t *testing.T) {
+	trie := newEmpty()
+	updateString(trie, "k", "v")
+
+	for i, key := range []string{"a", "j", "l", "z"} {
+		proof, err := trie.Prove([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(proof) != 1 {
+			t.Errorf("test %d: proof should have one element", i)
+		}
+		val, err := VerifyProof(trie.Hash(), []byte(key), proof)
+		if err != nil {
+			t.Fatalf("test %d: failed to verify proof: %v\nraw proof: %x", i, err, proof)
+		}
+		if val != nil {
+			t.Fatalf("test %d: verified value mismatch: have %x, want nil", i, val)
+		}
+	}
+}
+
+func TestBadProof(t *testing.T) {
+	trie, vals := randomTrie(800)
+	root := trie.Hash()
+	for k := range vals {
+		proof, _ := trie.Prove([]byte(k))
+		if len(proof) == 0 {
+			t.Fatal("zero length proof")
+		}
+		// Mutate a random node of the proof
+		i := mrand.Intn(len(proof))
+		mutated := make([][]byte, len(proof))
+		copy(mutated, proof)
+		node := common.CopyBytes(mutated[i])
+		node[mrand.Intn(len(node))] ^= byte(mrand.Intn(255) + 1)
+		mutated[i] = node
+		if _, err := VerifyProof(root, []byte(k), mutated); err == nil {
+			t.Fatalf("expected proof to fail for key %x", k)
+		}
+		// Drop a node of the proof
+		if _, err := VerifyProof(root, []byte(k), append(append([][]byte{}, proof[:i]...), proof[i+1:]...)); err == nil {
+			t.Fatalf("expected proof without node %d to fail for key %x", i, k)
+		}
+	}
+}
+
+func TestProveCommitted(t *testing.T) {
+	trie, vals := randomTrie(100)
+	root, err := trie.Commit(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The reopened trie resolves the nodes from the database while proving
+	reopened, err := NewTrie(root, trie.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range vals {
+		proof, err := reopened.Prove([]byte(k))
+		if err != nil {
+			t.Fatalf("prove %x error:%v", k, err)
+		}
+		if val, err := VerifyProof(root, []byte(k), proof); err != nil || !bytes.Equal(val, v) {
+			t.Fatalf("verify %x error:%v", k, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"github.com/xchain/go-chain/common"
+	"github.com/xchain/go-chain/storage/xchaindb"
+)
+
+// LeafChildren returns the hashes referenced by a leaf value of the trie besides the trie nodes,
+// e.g. the storage root and the code hash of an account
+type LeafChildren func(leaf []byte) []common.Hash
+
+// Prune deletes the nodes from the disk database which are not reachable from the given roots.
+// The hashes referenced by the leaves are kept as well, and traversed if they are tries.
+//
+// The disk database must be dedicated to the tries, as any hash-length key not reachable is
+// deleted. Pruning must not run concurrently with Commit, otherwise the newly flushed nodes
+// not reachable from the roots yet may be deleted.
+func (db *NodeDatabase) Prune(roots []common.Hash, children LeafChildren) (kept int, pruned int, err error) {
+	marked := make(map[common.Hash]struct{})
+	for _, root := range roots {
+		db.mark(root, children, marked)
+	}
+
+	iter := db.diskdb.NewIterator()
+	defer iter.Release()
+
+	batch := db.diskdb.NewBatch()
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != common.HashLength {
+			// Not a node, e.g. the preimage of the secure trie
+			continue
+		}
+		if _, ok := marked[common.BytesToHash(key)]; ok {
+			kept++
+			continue
+		}
+		if err = batch.Delete(common.CopyBytes(key)); err != nil {
+			return
+		}
+		pruned++
+		if batch.ValueSize() >= xchaindb.IdealBatchSize {
+			if err = batch.Write(); err != nil {
+				return
+			}
+			batch.Reset()
+		}
+	}
+	if err = iter.Error(); err != nil {
+		return
+	}
+	err = batch.Write()
+	return
+}
+
+// mark marks the node of the hash and all its descendants reachable
+func (db *NodeDatabase) mark(hash common.Hash, children LeafChildren, marked map[common.Hash]struct{}) {
+	if _, ok := marked[hash]; ok {
+		return
+	}
+	marked[hash] = struct{}{}
+	enc, err := db.Node(hash)
+	if err != nil || len(enc) == 0 {
+		return
+	}
+	n, err := decodeNode(hash[:], enc, 0)
+	if err != nil {
+		// Not a trie node, e.g. the contract code
+		return
+	}
+	db.markChildren(n, children, marked)
+}
+
+func (db *NodeDatabase) markChildren(n node, children LeafChildren, marked map[common.Hash]struct{}) {
+	switch n := n.(type) {
+	case *shortNode:
+		db.markChildren(n.Val, children, marked)
+	case *fullNode:
+		for _, child := range n.Children {
+			if child != nil {
+				db.markChildren(child, children, marked)
+			}
+		}
+	case hashNode:
+		db.mark(common.BytesToHash(n), children, marked)
+	case valueNode:
+		if children != nil {
+			for _, hash := range children(n) {
+				db.mark(hash, children, marked)
+			}
+		}
+	}
+}
@@ -0,0 +1,349 @@
+//   Copyright (C) 2019 XChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package xchaindb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/xchain/go-chain/common"
+)
+
+const boltFile = "bolt.db"
+
+var boltBucket = []byte("xchain")
+
+// boltIterChunk is the number of the entries the bolt iterator loads at a time
+var boltIterChunk = 1024
+
+// BoltDatabase is the Database stored in a bolt file, with all the keys in a single bucket
+type BoltDatabase struct {
+	db *bolt.DB
+}
+
+// NewBoltDatabase opens the bolt database in the directory, creating it if not exists
+func NewBoltDatabase(dir string) (*BoltDatabase, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(filepath.Join(dir, boltFile), 0666, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltDatabase{db: db}, nil
+}
+
+// Path returns the path of the bolt file
+func (db *BoltDatabase) Path() string {
+	return db.db.Path()
+}
+
+func (db *BoltDatabase) Put(key []byte, value []byte) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put(key, value)
+	})
+}
+
+// Get returns the value of the key, or errors.ErrNotFound as the LevelDB if the key doesn't exist
+func (db *BoltDatabase) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := db.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltBucket).Get(key)
+		if v == nil {
+			return errors.ErrNotFound
+		}
+		// The value is only valid in the transaction
+		value = make([]byte, len(v))
+		copy(value, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (db *BoltDatabase) Has(key []byte) (bool, error) {
+	var has bool
+	err := db.db.View(func(tx *bolt.Tx) error {
+		has = tx.Bucket(boltBucket).Get(key) != nil
+		return nil
+	})
+	return has, err
+}
+
+func (db *BoltDatabase) Delete(key []byte) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete(key)
+	})
+}
+
+func (db *BoltDatabase) Close() {
+	db.db.Close()
+}
+
+func (db *BoltDatabase) NewBatch() Batch {
+	return &boltBatch{db: db.db}
+}
+
+func (db *BoltDatabase) NewIterator() iterator.Iterator {
+	return db.NewIteratorWithPrefix(nil)
+}
+
+// NewIteratorWithPrefix returns a iterator over the keys with a particular prefix in the key order.
+// The iterator loads the entries in chunks without holding a transaction, so the database can be
+// written while iterating, but unlike the LevelDB one the writes after the iterator is created may
+// be seen
+func (db *BoltDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	return &boltIterator{db: db.db, prefix: common.CopyBytes(prefix), pos: -1}
+}
+
+// boltBatch applies the writes in a single transaction
+type boltBatch struct {
+	db     *bolt.DB
+	writes []kv
+	size   int
+}
+
+func (b *boltBatch) Put(key, value []byte) error {
+	b.writes = append(b.writes, kv{common.CopyBytes(key), common.CopyBytes(value), false})
+	b.size += len(value)
+	return nil
+}
+
+func (b *boltBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, kv{common.CopyBytes(key), nil, true})
+	b.size++
+	return nil
+}
+
+func (b *boltBatch) Write() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, kv := range b.writes {
+			var err error
+			if kv.del {
+				err = bucket.Delete(kv.k)
+			} else {
+				err = bucket.Put(kv.k, kv.v)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *boltBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *boltBatch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
+
+// boltIterator implements the LevelDB iterator contract: it's positioned before the first entry when
+// created, Next on the fresh iterator moves to the first entry and Prev to the last one, and once
+// moved out of the range, it's positioned before the first or after the last entry
+type boltIterator struct {
+	util.BasicReleaser
+	db     *bolt.DB
+	prefix []byte
+
+	keys   [][]byte // The chunk of the entries loaded in the key order, empty once moved out of the range
+	values [][]byte
+	pos    int // The position in the chunk, -1 before the first entry and len(keys) after the last
+	err    error
+}
+
+// load reads a chunk of the entries after the key in the forward direction, or before it in the
+// backward direction. The key is included if inclusive. The start or the end of the range is used
+// if the key is nil
+func (it *boltIterator) load(key []byte, inclusive bool, forward bool) bool {
+	it.keys, it.values = it.keys[:0], it.values[:0]
+	it.err = it.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		var k, v []byte
+		if forward {
+			if key == nil || bytes.Compare(key, it.prefix) < 0 {
+				key, inclusive = it.prefix, true
+			}
+			k, v = c.Seek(key)
+			if k != nil && !inclusive && bytes.Equal(k, key) {
+				k, v = c.Next()
+			}
+		} else {
+			if key == nil {
+				key, inclusive = util.BytesPrefix(it.prefix).Limit, false
+			}
+			if key == nil {
+				k, v = c.Last()
+			} else if k, v = c.Seek(key); k == nil {
+				k, v = c.Last()
+			} else if !inclusive || !bytes.Equal(k, key) {
+				k, v = c.Prev()
+			}
+		}
+		for ; k != nil && bytes.HasPrefix(k, it.prefix) && len(it.keys) < boltIterChunk; k, v = it.step(c, forward) {
+			it.keys = append(it.keys, common.CopyBytes(k))
+			it.values = append(it.values, common.CopyBytes(v))
+		}
+		return nil
+	})
+	if !forward {
+		for i, j := 0, len(it.keys)-1; i < j; i, j = i+1, j-1 {
+			it.keys[i], it.keys[j] = it.keys[j], it.keys[i]
+			it.values[i], it.values[j] = it.values[j], it.values[i]
+		}
+	}
+	return it.err == nil && len(it.keys) > 0
+}
+
+func (it *boltIterator) step(c *bolt.Cursor, forward bool) ([]byte, []byte) {
+	if forward {
+		return c.Next()
+	}
+	return c.Prev()
+}
+
+func (it *boltIterator) released() bool {
+	if it.Released() {
+		it.err = iterator.ErrIterReleased
+		return true
+	}
+	return false
+}
+
+func (it *boltIterator) First() bool {
+	if it.released() {
+		return false
+	}
+	if !it.load(nil, true, true) {
+		it.pos = -1
+		return false
+	}
+	it.pos = 0
+	return true
+}
+
+func (it *boltIterator) Last() bool {
+	if it.released() {
+		return false
+	}
+	if !it.load(nil, false, false) {
+		it.pos = 0
+		return false
+	}
+	it.pos = len(it.keys) - 1
+	return true
+}
+
+func (it *boltIterator) Seek(key []byte) bool {
+	if it.released() {
+		return false
+	}
+	if !it.load(key, true, true) {
+		it.pos = 0
+		return false
+	}
+	it.pos = 0
+	return true
+}
+
+func (it *boltIterator) Next() bool {
+	if it.released() {
+		return false
+	}
+	switch {
+	case it.pos < 0:
+		return it.First()
+	case it.pos >= len(it.keys):
+		return false
+	case it.pos+1 < len(it.keys):
+		it.pos++
+		return true
+	}
+	if !it.load(it.keys[it.pos], false, true) {
+		it.pos = 0
+		return false
+	}
+	it.pos = 0
+	return true
+}
+
+func (it *boltIterator) Prev() bool {
+	if it.released() {
+		return false
+	}
+	switch {
+	case it.pos >= len(it.keys):
+		return it.Last()
+	case it.pos < 0:
+		return false
+	case it.pos > 0:
+		it.pos--
+		return true
+	}
+	if !it.load(it.keys[0], false, false) {
+		it.pos = -1
+		return false
+	}
+	it.pos = len(it.keys) - 1
+	return true
+}
+
+func (it *boltIterator) Valid() bool {
+	return !it.Released() && it.pos >= 0 && it.pos < len(it.keys)
+}
+
+func (it *boltIterator) Key() []byte {
+	if !it.Valid() {
+		return nil
+	}
+	return it.keys[it.pos]
+}
+
+func (it *boltIterator) Value() []byte {
+	if !it.Valid() {
+		return nil
+	}
+	return it.values[it.pos]
+}
+
+func (it *boltIterator) Error() error {
+	return it.err
+}
+
+func (it *boltIterator) Release() {
+	it.keys, it.values = nil, nil
+	it.BasicReleaser.Release()
+}
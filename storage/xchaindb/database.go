@@ -25,8 +25,10 @@ import (
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/comparer"
 	"github.com/syndtr/goleveldb/leveldb/errors"
 	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/memdb"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/xchain/go-chain/common"
@@ -42,7 +44,7 @@ var (
 )
 
 type PrefixedDatabase struct {
-	db     *LDBDatabase
+	db     Database
 	prefix string
 }
 
@@ -105,7 +107,7 @@ func (db *PrefixedDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterat
 }
 
 func (db *PrefixedDatabase) NewBatch() Batch {
-	return &prefixBatch{db: db.db.db, b: new(leveldb.Batch), prefix: db.prefix}
+	return &prefixBatch{b: db.db.NewBatch(), prefix: db.prefix}
 }
 
 func (db *PrefixedDatabase) AddKv(batch Batch, k, v []byte) error {
@@ -174,6 +176,9 @@ func (iter *prefixIter) Error() error {
 
 func (iter *prefixIter) Key() []byte {
 	key := iter.iter.Key()
+	if key == nil {
+		return nil
+	}
 	return key[len(iter.prefix):]
 }
 
@@ -182,35 +187,28 @@ func (iter *prefixIter) Value() []byte {
 }
 
 type prefixBatch struct {
-	db     *leveldb.DB
-	b      *leveldb.Batch
-	size   int
+	b      Batch
 	prefix string
 }
 
 func (b *prefixBatch) Delete(key []byte) error {
-	b.b.Delete(generateKey(key, b.prefix))
-	b.size++
-	return nil
+	return b.b.Delete(generateKey(key, b.prefix))
 }
 
 func (b *prefixBatch) Put(key, value []byte) error {
-	b.b.Put(generateKey(key, b.prefix), value)
-	b.size += len(value)
-	return nil
+	return b.b.Put(generateKey(key, b.prefix), value)
 }
 
 func (b *prefixBatch) Write() error {
-	return b.db.Write(b.b, nil)
+	return b.b.Write()
 }
 
 func (b *prefixBatch) ValueSize() int {
-	return b.size
+	return b.b.ValueSize()
 }
 
 func (b *prefixBatch) Reset() {
 	b.b.Reset()
-	b.size = 0
 }
 
 // generateKey generate a prefixed key
@@ -442,12 +440,23 @@ func (db *MemDatabase) Keys() [][]byte {
 	return keys
 }
 
+// NewIterator returns a iterator over a snapshot of the database content in the key order
 func (db *MemDatabase) NewIterator() iterator.Iterator {
-	panic("Not support")
+	return db.NewIteratorWithPrefix(nil)
 }
 
+// NewIteratorWithPrefix returns a iterator over a snapshot of the database content with a particular prefix
 func (db *MemDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
-	panic("Not support")
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	snapshot := memdb.New(comparer.DefaultComparer, 0)
+	for key, value := range db.db {
+		if bytes.HasPrefix([]byte(key), prefix) {
+			snapshot.Put([]byte(key), value)
+		}
+	}
+	return snapshot.NewIterator(nil)
 }
 
 func (db *MemDatabase) Delete(key []byte) error {
@@ -15,19 +15,54 @@
 
 package xchaindb
 
-import "github.com/syndtr/goleveldb/leveldb/opt"
+import (
+	"fmt"
+
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+const (
+	BackendLevelDB = "leveldb"
+	BackendBolt    = "bolt"
+
+	DefaultBackend = BackendLevelDB
+)
+
+// Backend is the storage engine of the data sources created by NewDataSource
+var Backend = DefaultBackend
+
+// ValidBackend checks if the storage engine is supported
+func ValidBackend(backend string) bool {
+	return backend == BackendLevelDB || backend == BackendBolt
+}
 
 type XchainDataSource struct {
-	db *LDBDatabase
+	db Database
 }
 
-// NewDataSource create levedb instance by file
+// NewDataSource create the database instance by file on the configured backend
 func NewDataSource(file string, options *opt.Options) (*XchainDataSource, error) {
-	db, err := getInstance(file, options)
-	if err != nil {
-		return nil, err
+	return NewDataSourceWithBackend(Backend, file, options)
+}
+
+// NewDataSourceWithBackend create the database instance by file on the backend. The options only
+// apply to the LevelDB
+func NewDataSourceWithBackend(backend string, file string, options *opt.Options) (*XchainDataSource, error) {
+	switch backend {
+	case BackendLevelDB:
+		db, err := getInstance(file, options)
+		if err != nil {
+			return nil, err
+		}
+		return &XchainDataSource{db: db}, nil
+	case BackendBolt:
+		db, err := NewBoltDatabase(file)
+		if err != nil {
+			return nil, err
+		}
+		return &XchainDataSource{db: db}, nil
 	}
-	return &XchainDataSource{db: db}, nil
+	return nil, fmt.Errorf("unknown database backend %v", backend)
 }
 
 // NewPrefixDatabase create logical database by prefix
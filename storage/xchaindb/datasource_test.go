@@ -15,12 +15,43 @@
 package xchaindb
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 )
 
+var testBackends = []string{BackendLevelDB, BackendBolt}
+
+// runBackends runs the test against every backend. The LevelDB one is on the test directory, and the
+// others on the temporary directories
+func runBackends(t *testing.T, test func(t *testing.T, backend string, file string)) {
+	for _, backend := range testBackends {
+		t.Run(backend, func(t *testing.T) {
+			file := "test"
+			if backend != BackendLevelDB {
+				dir, err := ioutil.TempDir("", "xchaindb_"+backend)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.RemoveAll(dir)
+				file = dir
+			}
+			test(t, backend, file)
+		})
+	}
+}
+
 func TestCreateLDB(t *testing.T) {
+	runBackends(t, testCreateLDB)
+}
+
+func testCreateLDB(t *testing.T, backend string, file string) {
 	// 创建ldb实例
-	ds, err := NewDataSource("test", nil)
+	ds, err := NewDataSourceWithBackend(backend, file, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -102,8 +133,12 @@ func TestLRUMemDatabase(t *testing.T) {
 }
 
 func TestClearLDB(t *testing.T) {
+	runBackends(t, testClearLDB)
+}
+
+func testClearLDB(t *testing.T, backend string, file string) {
 	// 创建ldb实例
-	ds, err := NewDataSource("test", nil)
+	ds, err := NewDataSourceWithBackend(backend, file, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -127,7 +162,11 @@ func TestClearLDB(t *testing.T) {
 }
 
 func TestBatchPutVisiableBeforeWrite(t *testing.T) {
-	ds, err := NewDataSource("test", nil)
+	runBackends(t, testBatchPutVisiableBeforeWrite)
+}
+
+func testBatchPutVisiableBeforeWrite(t *testing.T, backend string, file string) {
+	ds, err := NewDataSourceWithBackend(backend, file, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -159,7 +198,11 @@ func TestBatchPutVisiableBeforeWrite(t *testing.T) {
 }
 
 func TestIteratorWithPrefix(t *testing.T) {
-	ds, err := NewDataSource("test", nil)
+	runBackends(t, testIteratorWithPrefix)
+}
+
+func testIteratorWithPrefix(t *testing.T, backend string, file string) {
+	ds, err := NewDataSourceWithBackend(backend, file, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -172,7 +215,11 @@ func TestIteratorWithPrefix(t *testing.T) {
 }
 
 func TestIteratorWithPrefix2(t *testing.T) {
-	ds, err := NewDataSource("test", nil)
+	runBackends(t, testIteratorWithPrefix2)
+}
+
+func testIteratorWithPrefix2(t *testing.T, backend string, file string) {
+	ds, err := NewDataSourceWithBackend(backend, file, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -190,7 +237,11 @@ func TestIteratorWithPrefix2(t *testing.T) {
 }
 
 func TestGetAfter(t *testing.T) {
-	ds, err := NewDataSource("test", nil)
+	runBackends(t, testGetAfter)
+}
+
+func testGetAfter(t *testing.T, backend string, file string) {
+	ds, err := NewDataSourceWithBackend(backend, file, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -201,3 +252,156 @@ func TestGetAfter(t *testing.T) {
 
 	}
 }
+
+func TestBatch(t *testing.T) {
+	runBackends(t, testBatch)
+}
+
+func testBatch(t *testing.T, backend string, file string) {
+	ds, err := NewDataSourceWithBackend(backend, file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, _ := ds.NewPrefixDatabase("testbatch")
+	defer db.Close()
+
+	db.Put([]byte("deleted"), []byte("value"))
+	batch := db.NewBatch()
+	batch.Put([]byte("key"), []byte("value"))
+	batch.Delete([]byte("deleted"))
+	if batch.ValueSize() == 0 {
+		t.Fatalf("batch size not counted")
+	}
+	if has, _ := db.Has([]byte("key")); has {
+		t.Fatalf("batch visible before write")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := db.Get([]byte("key")); !bytes.Equal(value, []byte("value")) {
+		t.Fatalf("batch put not written")
+	}
+	if has, _ := db.Has([]byte("deleted")); has {
+		t.Fatalf("batch delete not written")
+	}
+	if _, err := db.Get([]byte("deleted")); err == nil {
+		t.Fatalf("get deleted key should return error")
+	}
+
+	batch.Reset()
+	if batch.ValueSize() != 0 {
+		t.Fatalf("batch not reset")
+	}
+	batch.Delete([]byte("key"))
+	batch.Write()
+	if has, _ := db.Has([]byte("key")); has {
+		t.Fatalf("batch delete after reset not written")
+	}
+}
+
+func TestIteratorContract(t *testing.T) {
+	defer func(chunk int) { boltIterChunk = chunk }(boltIterChunk)
+	// Cross the chunk boundaries of the bolt iterator
+	boltIterChunk = 3
+
+	runBackends(t, testIteratorContract)
+
+	mem, _ := NewMemDatabase()
+	t.Run("memory", func(t *testing.T) {
+		checkIteratorContract(t, mem)
+	})
+}
+
+func testIteratorContract(t *testing.T, backend string, file string) {
+	ds, err := NewDataSourceWithBackend(backend, file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.db.Close()
+	checkIteratorContract(t, ds.db)
+}
+
+// checkIteratorContract checks the iterator of the database behaves as the LevelDB one on the keys
+// prefixed with "iter/"
+func checkIteratorContract(t *testing.T, db Database) {
+	var keys []string
+	for i := 0; i < 10; i++ {
+		keys = append(keys, fmt.Sprintf("iter/a%02d", i))
+	}
+	for _, k := range append(keys, "iter/b00") {
+		db.Put([]byte(k), []byte("v"+k))
+	}
+	defer func() {
+		for _, k := range append(keys, "iter/b00") {
+			db.Delete([]byte(k))
+		}
+	}()
+
+	check := func(it iterator.Iterator, ok bool, key string) {
+		t.Helper()
+		if it.Valid() != ok {
+			t.Fatalf("expect valid %v", ok)
+		}
+		if !ok {
+			if it.Key() != nil {
+				t.Fatalf("invalid iterator with key %s", it.Key())
+			}
+			return
+		}
+		if string(it.Key()) != key || string(it.Value()) != "v"+key {
+			t.Fatalf("expect %v, got %s:%s", key, it.Key(), it.Value())
+		}
+	}
+
+	it := db.NewIteratorWithPrefix([]byte("iter/a"))
+	check(it, false, "")
+	// Forward from the fresh iterator
+	for _, k := range keys {
+		check(it, it.Next(), k)
+	}
+	check(it, it.Next(), "")
+	if it.Next() {
+		t.Fatalf("next after the last entry")
+	}
+	// Backward from after the last entry
+	for i := len(keys) - 1; i >= 0; i-- {
+		check(it, it.Prev(), keys[i])
+	}
+	check(it, it.Prev(), "")
+	// Forward from before the first entry
+	check(it, it.Next(), keys[0])
+	it.Release()
+
+	it = db.NewIteratorWithPrefix([]byte("iter/a"))
+	check(it, it.Prev(), keys[len(keys)-1])
+	check(it, it.First(), keys[0])
+	check(it, it.Last(), keys[len(keys)-1])
+	check(it, it.Seek([]byte("iter/a045")), "iter/a05")
+	check(it, it.Prev(), "iter/a04")
+	check(it, it.Seek([]byte("iter/a07")), "iter/a07")
+	check(it, it.Next(), "iter/a08")
+	check(it, it.Seek([]byte("0")), keys[0])
+	check(it, it.Seek([]byte("iter/a10")), "")
+	check(it, it.Prev(), keys[len(keys)-1])
+	it.Release()
+	if it.Next() || it.Error() == nil {
+		t.Fatalf("released iterator should fail")
+	}
+
+	it = db.NewIteratorWithPrefix([]byte("iter/"))
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if count != len(keys)+1 || it.Error() != nil {
+		t.Fatalf("unexpected entries %v, error %v", count, it.Error())
+	}
+	it.Release()
+
+	// Empty range
+	it = db.NewIteratorWithPrefix([]byte("iter/c"))
+	if it.First() || it.Last() || it.Next() || it.Prev() {
+		t.Fatalf("empty range iterated")
+	}
+	it.Release()
+}